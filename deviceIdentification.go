@@ -0,0 +1,429 @@
+package epsolar
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/goburrow/serial"
+	"github.com/simonvetter/modbus"
+)
+
+// github.com/simonvetter/modbus does not support Read Device Identification (function 0x2B/0x0E) and does not
+// expose its link, so ModbusClientTransport performs the request over a link of its own, opened from the
+// client configuration while the client is closed.
+
+const (
+	functionEncapsulatedInterface = 0x2b
+	meiReadDeviceIdentification   = 0x0e
+
+	readDeviceIdBasic   = 0x01
+	readDeviceIdRegular = 0x02
+)
+
+type identificationConn interface {
+	io.ReadWriteCloser
+	SetDeadline(t time.Time) error
+}
+
+// identificationLink is a connection to the device framed as either Modbus RTU or Modbus TCP.
+type identificationLink struct {
+	conn          identificationConn
+	rtu           bool
+	timeout       time.Duration
+	transactionId uint16
+}
+
+func openIdentificationLink(conf *modbus.ClientConfiguration) (*identificationLink, error) {
+	scheme, address, ok := strings.Cut(conf.URL, "://")
+	if !ok {
+		return nil, modbus.ErrConfigurationError
+	}
+
+	timeout := conf.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Second
+	}
+
+	var err error
+	link := &identificationLink{
+		timeout: timeout,
+	}
+	switch scheme {
+	case "rtu":
+		if conf.Timeout == 0 {
+			link.timeout = 300 * time.Millisecond
+		}
+		link.rtu = true
+		link.conn, err = openSerialConn(conf, address)
+	case "rtuovertcp":
+		link.rtu = true
+		link.conn, err = net.DialTimeout("tcp", address, 5*time.Second)
+	case "rtuoverudp":
+		link.rtu = true
+		link.conn, err = dialUDP(address)
+	case "tcp":
+		link.conn, err = net.DialTimeout("tcp", address, 5*time.Second)
+	case "tcp+tls":
+		if (conf.TLSClientCert == nil) || (conf.TLSRootCAs == nil) {
+			return nil, modbus.ErrConfigurationError
+		}
+		link.conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 15 * time.Second}, "tcp", address, &tls.Config{
+			Certificates: []tls.Certificate{*conf.TLSClientCert},
+			RootCAs:      conf.TLSRootCAs,
+			MinVersion:   tls.VersionTLS12,
+		})
+	case "udp":
+		link.conn, err = dialUDP(address)
+	default:
+		return nil, modbus.ErrConfigurationError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func (link *identificationLink) Close() error {
+	return link.conn.Close()
+}
+
+// request sends pdu to unitId and returns the response PDU. Exception responses are returned as errors.
+func (link *identificationLink) request(unitId uint8, pdu []byte) ([]byte, error) {
+	err := link.conn.SetDeadline(time.Now().Add(link.timeout))
+	if err != nil {
+		return nil, err
+	}
+	var res []byte
+	if link.rtu {
+		res, err = link.requestRTU(unitId, pdu)
+	} else {
+		res, err = link.requestTCP(unitId, pdu)
+	}
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, modbus.ErrRequestTimedOut
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, modbus.ErrShortFrame
+		}
+		return nil, err
+	}
+	if len(res) < 2 {
+		return nil, modbus.ErrShortFrame
+	}
+	if res[0] == pdu[0]|0x80 {
+		return nil, exceptionError(res[1])
+	}
+	if res[0] != pdu[0] {
+		return nil, modbus.ErrProtocolError
+	}
+	return res, nil
+}
+
+func (link *identificationLink) requestRTU(unitId uint8, pdu []byte) ([]byte, error) {
+	frame := append([]byte{unitId}, pdu...)
+	frame = binary.LittleEndian.AppendUint16(frame, crc16(frame))
+	_, err := link.conn.Write(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	// RTU frames carry no length, so the response is read according to its function code.
+	var buf bytes.Buffer
+	r := io.TeeReader(link.conn, &buf)
+	header := make([]byte, 2)
+	_, err = io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case header[1]&0x80 != 0:
+		_, err = io.ReadFull(r, make([]byte, 1))
+	case header[1] == functionEncapsulatedInterface:
+		err = readDeviceIdentificationBody(r)
+	default:
+		return nil, modbus.ErrProtocolError
+	}
+	if err != nil {
+		return nil, err
+	}
+	crc := make([]byte, 2)
+	_, err = io.ReadFull(link.conn, crc)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(crc) != crc16(buf.Bytes()) {
+		return nil, modbus.ErrBadCRC
+	}
+	if header[0] != unitId {
+		return nil, modbus.ErrBadUnitId
+	}
+	return buf.Bytes()[1:], nil
+}
+
+// readDeviceIdentificationBody consumes the remainder of a Read Device Identification response PDU.
+func readDeviceIdentificationBody(r io.Reader) error {
+	header := make([]byte, 6)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(header[5]); i++ {
+		object := make([]byte, 2)
+		_, err = io.ReadFull(r, object)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadFull(r, make([]byte, object[1]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (link *identificationLink) requestTCP(unitId uint8, pdu []byte) ([]byte, error) {
+	link.transactionId++
+	frame := binary.BigEndian.AppendUint16(nil, link.transactionId)
+	frame = binary.BigEndian.AppendUint16(frame, 0)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(pdu)+1))
+	frame = append(frame, unitId)
+	frame = append(frame, pdu...)
+	_, err := link.conn.Write(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	_, err = io.ReadFull(link.conn, header)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[4:6])
+	if (length < 2) || (length > 254) {
+		return nil, modbus.ErrProtocolError
+	}
+	res := make([]byte, length-1)
+	_, err = io.ReadFull(link.conn, res)
+	if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:2]) != link.transactionId {
+		return nil, modbus.ErrBadTransactionId
+	}
+	if binary.BigEndian.Uint16(header[2:4]) != 0 {
+		return nil, modbus.ErrUnknownProtocolId
+	}
+	if header[6] != unitId {
+		return nil, modbus.ErrBadUnitId
+	}
+	return res, nil
+}
+
+// readDeviceIdentification reads the regular device identification objects, falling back to the basic
+// objects if the device does not support the regular category.
+func readDeviceIdentification(link *identificationLink, unitId uint8) (map[DeviceIdentificationObject]string, error) {
+	objects := make(map[DeviceIdentificationObject]string)
+	code := byte(readDeviceIdRegular)
+	objectId := byte(0)
+	for {
+		res, err := link.request(unitId, []byte{functionEncapsulatedInterface, meiReadDeviceIdentification, code, objectId})
+		if err != nil {
+			if (code == readDeviceIdRegular) && (objectId == 0) && errors.Is(err, modbus.ErrIllegalDataValue) {
+				code = readDeviceIdBasic
+				continue
+			}
+			return nil, err
+		}
+		more, next, err := decodeDeviceIdentification(res, objects)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return objects, nil
+		}
+		if next <= objectId {
+			return nil, modbus.ErrProtocolError
+		}
+		objectId = next
+	}
+}
+
+// decodeDeviceIdentification adds the objects in a Read Device Identification response PDU to objects, and
+// returns whether more objects follow and, if so, the ID of the next one.
+func decodeDeviceIdentification(res []byte, objects map[DeviceIdentificationObject]string) (bool, byte, error) {
+	if len(res) < 7 {
+		return false, 0, modbus.ErrShortFrame
+	}
+	if res[1] != meiReadDeviceIdentification {
+		return false, 0, modbus.ErrProtocolError
+	}
+	more := res[4] == 0xff
+	next := res[5]
+	offset := 7
+	for i := 0; i < int(res[6]); i++ {
+		if offset+2 > len(res) {
+			return false, 0, modbus.ErrShortFrame
+		}
+		id := DeviceIdentificationObject(res[offset])
+		length := int(res[offset+1])
+		offset += 2
+		if offset+length > len(res) {
+			return false, 0, modbus.ErrShortFrame
+		}
+		objects[id] = string(res[offset : offset+length])
+		offset += length
+	}
+	return more, next, nil
+}
+
+func exceptionError(code byte) error {
+	switch code {
+	case 0x01:
+		return modbus.ErrIllegalFunction
+	case 0x02:
+		return modbus.ErrIllegalDataAddress
+	case 0x03:
+		return modbus.ErrIllegalDataValue
+	case 0x04:
+		return modbus.ErrServerDeviceFailure
+	case 0x05:
+		return modbus.ErrAcknowledge
+	case 0x06:
+		return modbus.ErrServerDeviceBusy
+	case 0x08:
+		return modbus.ErrMemoryParityError
+	case 0x0a:
+		return modbus.ErrGWPathUnavailable
+	case 0x0b:
+		return modbus.ErrGWTargetFailedToRespond
+	default:
+		return fmt.Errorf("unknown exception code (%d)", code)
+	}
+}
+
+func crc16(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// ---
+
+func dialUDP(address string) (identificationConn, error) {
+	conn, err := net.DialTimeout("udp", address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &udpConn{Conn: conn}, nil
+}
+
+// udpConn lets a response datagram be read a few bytes at a time.
+type udpConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *udpConn) Read(b []byte) (int, error) {
+	if c.buf.Len() == 0 {
+		datagram := make([]byte, 1024)
+		n, err := c.Conn.Read(datagram)
+		if err != nil {
+			return 0, err
+		}
+		c.buf.Write(datagram[:n])
+	}
+	return c.buf.Read(b)
+}
+
+// serialConn adds read deadline support to a serial port.
+type serialConn struct {
+	port     serial.Port
+	deadline time.Time
+}
+
+func openSerialConn(conf *modbus.ClientConfiguration, device string) (*serialConn, error) {
+	speed := conf.Speed
+	if speed == 0 {
+		speed = 19200
+	}
+	dataBits := conf.DataBits
+	if dataBits == 0 {
+		dataBits = 8
+	}
+	stopBits := conf.StopBits
+	if stopBits == 0 {
+		if conf.Parity == modbus.PARITY_NONE {
+			stopBits = 2
+		} else {
+			stopBits = 1
+		}
+	}
+	var parity string
+	switch conf.Parity {
+	case modbus.PARITY_NONE:
+		parity = "N"
+	case modbus.PARITY_EVEN:
+		parity = "E"
+	case modbus.PARITY_ODD:
+		parity = "O"
+	}
+	port, err := serial.Open(&serial.Config{
+		Address:  device,
+		BaudRate: int(speed),
+		DataBits: int(dataBits),
+		Parity:   parity,
+		StopBits: int(stopBits),
+		Timeout:  10 * time.Millisecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &serialConn{port: port}, nil
+}
+
+func (c *serialConn) Read(b []byte) (int, error) {
+	for {
+		if time.Now().After(c.deadline) {
+			return 0, modbus.ErrRequestTimedOut
+		}
+		n, err := c.port.Read(b)
+		if errors.Is(err, serial.ErrTimeout) {
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *serialConn) Write(b []byte) (int, error) {
+	return c.port.Write(b)
+}
+
+func (c *serialConn) Close() error {
+	return c.port.Close()
+}
+
+func (c *serialConn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
@@ -0,0 +1,251 @@
+package epsolar
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/simonvetter/modbus"
+)
+
+// identificationServer answers Read Device Identification requests over Modbus TCP or RTU over TCP, reporting
+// objects in two responses. Any other function is answered with an illegal function exception.
+type identificationServer struct {
+	listener net.Listener
+	rtu      bool
+	objects  [][]byte // id, length, value
+	requests atomic.Int32
+}
+
+func newIdentificationServer(t *testing.T, rtu bool) *identificationServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &identificationServer{
+		listener: listener,
+		rtu:      rtu,
+	}
+	for id, value := range []string{"EPEVER", "Tracer3210AN", "V02.13", "", "", "Tracer3210AN"} {
+		if value != "" {
+			s.objects = append(s.objects, append([]byte{byte(id), byte(len(value))}, value...))
+		}
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return s
+}
+
+func (s *identificationServer) url() string {
+	if s.rtu {
+		return "rtuovertcp://" + s.listener.Addr().String()
+	}
+	return "tcp://" + s.listener.Addr().String()
+}
+
+func (s *identificationServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer func(conn net.Conn) {
+				_ = conn.Close()
+			}(conn)
+			for s.handle(conn) == nil {
+			}
+		}()
+	}
+}
+
+func (s *identificationServer) handle(conn net.Conn) error {
+	var header []byte
+	var pdu []byte
+	if s.rtu {
+		header = make([]byte, 2) // unit ID, function code
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			return err
+		}
+		length := 4 // address, quantity
+		if header[1] == functionEncapsulatedInterface {
+			length = 3 // MEI type, read device ID code, object ID
+		}
+		tail := make([]byte, length+2)
+		_, err = io.ReadFull(conn, tail)
+		if err != nil {
+			return err
+		}
+		pdu = append(header[1:2:2], tail[:length]...)
+	} else {
+		header = make([]byte, 7)
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			return err
+		}
+		pdu = make([]byte, binary.BigEndian.Uint16(header[4:6])-1)
+		_, err = io.ReadFull(conn, pdu)
+		if err != nil {
+			return err
+		}
+	}
+
+	var res []byte
+	if (pdu[0] == functionEncapsulatedInterface) && (pdu[1] == meiReadDeviceIdentification) {
+		s.requests.Add(1)
+		objects := s.objects[:3]
+		more, next := byte(0xff), byte(0x05)
+		if pdu[3] != 0 {
+			objects = s.objects[3:]
+			more, next = 0x00, 0x00
+		}
+		res = []byte{pdu[0], pdu[1], pdu[2], 0x82, more, next, byte(len(objects))}
+		for _, object := range objects {
+			res = append(res, object...)
+		}
+	} else {
+		res = []byte{pdu[0] | 0x80, 0x01}
+	}
+
+	var frame []byte
+	if s.rtu {
+		frame = append([]byte{header[0]}, res...)
+		frame = binary.LittleEndian.AppendUint16(frame, crc16(frame))
+	} else {
+		frame = binary.BigEndian.AppendUint16(header[:4:4], uint16(len(res)+1))
+		frame = append(frame, header[6])
+		frame = append(frame, res...)
+	}
+	_, err := conn.Write(frame)
+	return err
+}
+
+func TestModbusClientTransportReadDeviceInfo(t *testing.T) {
+	for _, rtu := range []bool{false, true} {
+		s := newIdentificationServer(t, rtu)
+		conf := &modbus.ClientConfiguration{
+			URL: s.url(),
+		}
+		mc, err := modbus.NewClient(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mc.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dev := NewWithTransport(NewModbusClientTransportWithConfiguration(mc, conf), 1, &sync.Mutex{})
+		deviceInfo, err := dev.ReadDeviceInfo()
+		if err != nil {
+			t.Fatalf("%s: %v", s.url(), err)
+		}
+		for name, v := range map[string]*string{
+			"VendorName":      deviceInfo.VendorName,
+			"ProductCode":     deviceInfo.ProductCode,
+			"ModelName":       deviceInfo.ModelName,
+			"FirmwareVersion": deviceInfo.FirmwareVersion,
+		} {
+			if v == nil {
+				t.Errorf("%s: %s not reported", s.url(), name)
+			}
+		}
+		if (deviceInfo.ModelName != nil) && (*deviceInfo.ModelName != "Tracer3210AN") {
+			t.Errorf("%s: ModelName = %s, want Tracer3210AN", s.url(), *deviceInfo.ModelName)
+		}
+
+		// the result is cached
+		_, err = dev.ReadDeviceInfo()
+		if err != nil {
+			t.Fatalf("%s: %v", s.url(), err)
+		}
+		if requests := s.requests.Load(); requests != 2 {
+			t.Errorf("%s: %d requests, want 2", s.url(), requests)
+		}
+
+		// the client is reopened after the request
+		_, err = dev.ReadRealTimeData()
+		if !errors.Is(err, modbus.ErrIllegalFunction) {
+			t.Errorf("%s: ReadRealTimeData after ReadDeviceInfo: %v, want %v", s.url(), err, modbus.ErrIllegalFunction)
+		}
+
+		_ = mc.Close()
+	}
+}
+
+func TestModbusClientTransportWithoutConfiguration(t *testing.T) {
+	s := newIdentificationServer(t, false)
+	mc, err := modbus.NewClient(&modbus.ClientConfiguration{
+		URL: s.url(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := New(mc, 1, &sync.Mutex{})
+	deviceInfo, err := dev.ReadDeviceInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deviceInfo != (DeviceInfo{}) {
+		t.Errorf("unexpected device info: %+v", deviceInfo)
+	}
+}
+
+func TestModbusClientTransportReadDeviceIdentificationOnce(t *testing.T) {
+	s := newIdentificationServer(t, false)
+	conf := &modbus.ClientConfiguration{
+		URL: s.url(),
+	}
+	mc, err := modbus.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mc.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(mc *modbus.ModbusClient) {
+		_ = mc.Close()
+	}(mc)
+
+	transport := NewModbusClientTransportWithConfiguration(mc, conf)
+	for i := 0; i < 2; i++ {
+		_, err = transport.ReadDeviceIdentification()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests := s.requests.Load(); requests != 2 {
+		t.Errorf("%d requests, want 2", requests)
+	}
+}
+
+func TestModbusClientTransportReopenFailure(t *testing.T) {
+	s := newIdentificationServer(t, false)
+	conf := &modbus.ClientConfiguration{
+		URL: s.url(),
+	}
+	mc, err := modbus.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mc.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.listener.Close()
+
+	_, err = NewModbusClientTransportWithConfiguration(mc, conf).ReadDeviceIdentification()
+	if (err == nil) || !strings.Contains(err.Error(), "reopening Modbus client") {
+		t.Errorf("err = %v, want reopen failure", err)
+	}
+}
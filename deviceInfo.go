@@ -0,0 +1,40 @@
+package epsolar
+
+// DeviceIdentificationObject is a Modbus Read Device Identification object ID.
+type DeviceIdentificationObject uint8
+
+const (
+	DeviceIdentificationVendorName         DeviceIdentificationObject = 0x00
+	DeviceIdentificationProductCode        DeviceIdentificationObject = 0x01
+	DeviceIdentificationMajorMinorRevision DeviceIdentificationObject = 0x02
+	DeviceIdentificationVendorUrl          DeviceIdentificationObject = 0x03
+	DeviceIdentificationProductName        DeviceIdentificationObject = 0x04
+	DeviceIdentificationModelName          DeviceIdentificationObject = 0x05
+)
+
+// DeviceInfo identifies the controller. Fields are nil if the controller does not report them.
+type DeviceInfo struct {
+	VendorName      *string
+	ProductCode     *string
+	ModelName       *string
+	FirmwareVersion *string
+}
+
+func decodeDeviceInfo(objects map[DeviceIdentificationObject]string) DeviceInfo {
+	var r DeviceInfo
+	get := func(id DeviceIdentificationObject) *string {
+		v, ok := objects[id]
+		if !ok {
+			return nil
+		}
+		return &v
+	}
+	r.VendorName = get(DeviceIdentificationVendorName)
+	r.ProductCode = get(DeviceIdentificationProductCode)
+	r.ModelName = get(DeviceIdentificationModelName)
+	if r.ModelName == nil {
+		r.ModelName = get(DeviceIdentificationProductName)
+	}
+	r.FirmwareVersion = get(DeviceIdentificationMajorMinorRevision)
+	return r
+}
@@ -0,0 +1,41 @@
+package epsolar_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+	"github.com/simonvetter/modbus"
+)
+
+// silentController does not answer Read Device Identification requests.
+type silentController struct {
+	*simulator.Controller
+	requests int
+}
+
+func (c *silentController) ReadDeviceIdentification() (map[epsolar.DeviceIdentificationObject]string, error) {
+	c.requests++
+	return nil, modbus.ErrRequestTimedOut
+}
+
+func TestReadDeviceInfoTimeout(t *testing.T) {
+	c := &silentController{
+		Controller: simulator.New(),
+	}
+	dev := epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+	dev.SetRetryPolicy(epsolar.DefaultRetryPolicy())
+	for i := 0; i < 3; i++ {
+		deviceInfo, err := dev.ReadDeviceInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deviceInfo != (epsolar.DeviceInfo{}) {
+			t.Errorf("unexpected device info: %+v", deviceInfo)
+		}
+	}
+	if c.requests != 1 {
+		t.Errorf("%d requests, want 1", c.requests)
+	}
+}
@@ -1,87 +1,139 @@
 package epsolar
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/simonvetter/modbus"
 )
 
+// Dev is an EPEVER solar charge controller on a Modbus bus.
+//
+// Each Read, Write and Set method has a Context variant that honours cancellation and deadlines while
+// waiting for the shared mutex and before each Modbus transaction. A transaction that is already in
+// progress is bounded by the Modbus client timeout.
+//
+// Transactions that fail with a transient error are retried according to the RetryPolicy set with
+// SetRetryPolicy. By default, no retries are made. A minimum gap between transactions on the bus may be set
+// with SetInterFrameDelay. Addresses the controller does not implement can be skipped by setting a Profile.
 type Dev struct {
-	mc     *modbus.ModbusClient
-	unitId uint8
-	mutex  *sync.Mutex
+	mc          Transport
+	unitId      uint8
+	mutex       *sync.Mutex
+	bus         *busState
+	retryPolicy RetryPolicy
+	profile     *Profile
+	deviceInfo  *DeviceInfo
 }
 
 func New(mc *modbus.ModbusClient, unitId uint8, mutex *sync.Mutex) *Dev {
+	return NewWithTransport(NewModbusClientTransport(mc), unitId, mutex)
+}
+
+func NewWithTransport(transport Transport, unitId uint8, mutex *sync.Mutex) *Dev {
 	return &Dev{
-		mc:     mc,
+		mc:     transport,
 		unitId: unitId,
 		mutex:  mutex,
+		bus:    busStateFor(mutex),
 	}
 }
 
+// SetRetryPolicy sets the policy used to retry transient Modbus errors.
+func (dev *Dev) SetRetryPolicy(policy RetryPolicy) {
+	dev.retryPolicy = policy
+}
+
 func (dev *Dev) requestSetup() error {
-	err := dev.mc.SetUnitId(dev.unitId)
-	if err != nil {
-		return err
-	}
-	err = dev.mc.SetEncoding(modbus.BIG_ENDIAN, modbus.LOW_WORD_FIRST)
-	if err != nil {
-		return err
+	return dev.mc.SetUnitId(dev.unitId)
+}
+
+// lock acquires the shared mutex, giving up if ctx is done first.
+func (dev *Dev) lock(ctx context.Context) error {
+	if dev.mutex.TryLock() {
+		return nil
+	}
+	locked := make(chan struct{})
+	go func() {
+		dev.mutex.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			dev.mutex.Unlock()
+		}()
+		return ctx.Err()
 	}
-	return nil
 }
 
 func (dev *Dev) ReadRatedData() (RatedData, error) {
-	dev.mutex.Lock()
+	return dev.ReadRatedDataContext(context.Background())
+}
+
+func (dev *Dev) ReadRatedDataContext(ctx context.Context) (RatedData, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return RatedData{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return RatedData{}, err
 	}
 
 	var r RatedData
 
-	r.ArrayRatedVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x3000, 100)
+	b, err := dev.readRegisterBlock(ctx, 0x3000, 17, InputRegister)
+	if err != nil {
+		return RatedData{}, err
+	}
+	r.ArrayRatedVoltage, err = b.uint16ToFloat64(ctx, 0x3000, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.ArrayRatedCurrent, err = dev.readInputRegisterFromUint16ToFloat64(0x3001, 100)
+	r.ArrayRatedCurrent, err = b.uint16ToFloat64(ctx, 0x3001, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.ArrayRatedPower, err = dev.readInputRegisterFromUint32ToFloat64(0x3002, 100)
+	r.ArrayRatedPower, err = b.uint32ToFloat64(ctx, 0x3002, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.BatteryRatedVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x3004, 100)
+	r.BatteryRatedVoltage, err = b.uint16ToFloat64(ctx, 0x3004, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.BatteryRatedCurrent, err = dev.readInputRegisterFromUint16ToFloat64(0x3005, 100)
+	r.BatteryRatedCurrent, err = b.uint16ToFloat64(ctx, 0x3005, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.BatteryRatedPower, err = dev.readInputRegisterFromUint32ToFloat64(0x3006, 100)
+	r.BatteryRatedPower, err = b.uint32ToFloat64(ctx, 0x3006, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.LoadRatedVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x300d, 100)
+	r.LoadRatedVoltage, err = b.uint16ToFloat64(ctx, 0x300d, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.LoadRatedCurrent, err = dev.readInputRegisterFromUint16ToFloat64(0x300e, 100)
+	r.LoadRatedCurrent, err = b.uint16ToFloat64(ctx, 0x300e, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.LoadRatedPower, err = dev.readInputRegisterFromUint32ToFloat64(0x300f, 100)
+	r.LoadRatedPower, err = b.uint32ToFloat64(ctx, 0x300f, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
-	r.BatteryRealRatedVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x311d, 100)
+	r.BatteryRealRatedVoltage, err = dev.readInputRegisterFromUint16ToFloat64(ctx, 0x311d, 100)
 	if err != nil {
 		return RatedData{}, err
 	}
@@ -90,10 +142,17 @@ func (dev *Dev) ReadRatedData() (RatedData, error) {
 }
 
 func (dev *Dev) ReadParameters() (Parameters, error) {
-	dev.mutex.Lock()
+	return dev.ReadParametersContext(context.Background())
+}
+
+func (dev *Dev) ReadParametersContext(ctx context.Context) (Parameters, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return Parameters{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return Parameters{}, err
 	}
@@ -101,7 +160,7 @@ func (dev *Dev) ReadParameters() (Parameters, error) {
 	var r Parameters
 
 	{
-		v, err := dev.mc.ReadRegister(0x9000, modbus.HOLDING_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x9000, HoldingRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return Parameters{}, err
@@ -111,64 +170,100 @@ func (dev *Dev) ReadParameters() (Parameters, error) {
 			r.BatteryType = &v2
 		}
 	}
-	r.BatteryCapacity, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9001, 1)
+	r.BatteryCapacity, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9001, 1)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.TemperatureCompensationCoefficient, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9002, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.OverVoltageDisconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9003, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.TemperatureCompensationCoefficient, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9002, 100)
+	r.ChargingLimitVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9004, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.OverVoltageDisconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9003, 100)
+	r.OverVoltageReconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9005, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.ChargingLimitVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9004, 100)
+	r.EqualizeChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9006, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.OverVoltageReconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9005, 100)
+	r.BoostChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9007, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.EqualizeChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9006, 100)
+	r.FloatChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9008, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.BoostChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9007, 100)
+	r.BoostReconnectChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9009, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.FloatChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9008, 100)
+	r.LowVoltageReconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x900a, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.BoostReconnectChargingVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x9009, 100)
+	r.UnderVoltageWarningRecoverVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x900b, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.LowVoltageReconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x900a, 100)
+	r.UnderVoltageWarningVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x900c, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.UnderVoltageWarningRecoverVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x900b, 100)
+	r.LowVoltageDisconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x900d, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.UnderVoltageWarningVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x900c, 100)
+	r.DischargingLimitVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x900e, 100)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.LowVoltageDisconnectVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x900d, 100)
+	r.EqualizeChargingCycle, err = dev.readHoldingRegister(ctx, 0x9016)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.DischargingLimitVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(0x900e, 100)
+	r.BatteryTemperatureWarningUpperLimit, err = dev.readHoldingRegisterFromInt16ToFloat64(ctx, 0x9017, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.BatteryTemperatureWarningLowerLimit, err = dev.readHoldingRegisterFromInt16ToFloat64(ctx, 0x9018, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.ControllerInnerTemperatureUpperLimit, err = dev.readHoldingRegisterFromInt16ToFloat64(ctx, 0x9019, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.ControllerInnerTemperatureUpperLimitRecover, err = dev.readHoldingRegisterFromInt16ToFloat64(ctx, 0x901a, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.NightTimeThresholdVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x901e, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.LightSignalStartupDelayTime, err = dev.readHoldingRegister(ctx, 0x901f)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.DayTimeThresholdVoltage, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x9020, 100)
+	if err != nil {
+		return Parameters{}, err
+	}
+	r.LightSignalTurnOffDelayTime, err = dev.readHoldingRegister(ctx, 0x9021)
 	if err != nil {
 		return Parameters{}, err
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x9067, modbus.HOLDING_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x9067, HoldingRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return Parameters{}, err
@@ -178,28 +273,28 @@ func (dev *Dev) ReadParameters() (Parameters, error) {
 			r.BatteryRatedVoltageLevel = &v2
 		}
 	}
-	r.DefaultLoadOnOffInManualMode, err = dev.readHoldingRegister(0x906a)
+	r.DefaultLoadOnOffInManualMode, err = dev.readHoldingRegister(ctx, 0x906a)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.EqualizeDuration, err = dev.readHoldingRegister(0x906b)
+	r.EqualizeDuration, err = dev.readHoldingRegister(ctx, 0x906b)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.BoostDuration, err = dev.readHoldingRegister(0x906c)
+	r.BoostDuration, err = dev.readHoldingRegister(ctx, 0x906c)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.BatteryDischarge, err = dev.readHoldingRegisterFromUint16ToFloat64(0x906d, 100) // NOTE: possibly incorrect documentation (divisor)
+	r.BatteryDischarge, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x906d, 100) // NOTE: possibly incorrect documentation (divisor)
 	if err != nil {
 		return Parameters{}, err
 	}
-	r.BatteryCharge, err = dev.readHoldingRegisterFromUint16ToFloat64(0x906e, 100) // NOTE: possibly incorrect documentation (divisor)
+	r.BatteryCharge, err = dev.readHoldingRegisterFromUint16ToFloat64(ctx, 0x906e, 100) // NOTE: possibly incorrect documentation (divisor)
 	if err != nil {
 		return Parameters{}, err
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x9070, modbus.HOLDING_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x9070, HoldingRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return Parameters{}, err
@@ -210,7 +305,7 @@ func (dev *Dev) ReadParameters() (Parameters, error) {
 		}
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x9107, modbus.HOLDING_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x9107, HoldingRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return Parameters{}, err
@@ -225,58 +320,353 @@ func (dev *Dev) ReadParameters() (Parameters, error) {
 	return r, nil
 }
 
+// WriteParameters writes the non-nil fields of p to the corresponding holding registers.
+// Fields are written in register order, using the same divisors as ReadParameters. Fields in the battery
+// voltage settings block (0x9000-0x900e) are written as a single block, as described in WriteBatteryVoltageSettings.
+// All other fields are written one register at a time. LiBatteryProtectionAndOverTemperatureDropPower is written
+// as described in WriteLiBatteryProtectionAndOverTemperatureDropPower. If any temperature limit (0x9017-0x901a) is
+// set, the current limits are read, merged with p and checked with Parameters.Validate before anything is written;
+// a *ValidationError is returned if any ordering rule is violated.
+func (dev *Dev) WriteParameters(p Parameters) error {
+	return dev.WriteParametersContext(context.Background(), p)
+}
+
+func (dev *Dev) WriteParametersContext(ctx context.Context, p Parameters) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return err
+	}
+
+	if p.hasTemperatureLimits() {
+		err = dev.validateTemperatureLimits(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+	if p.hasBatteryVoltageSettings() {
+		err = dev.writeBatteryVoltageSettings(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+	err = dev.writeHoldingRegister(ctx, 0x9016, p.EqualizeChargingCycle)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToInt16(ctx, 0x9017, p.BatteryTemperatureWarningUpperLimit, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToInt16(ctx, 0x9018, p.BatteryTemperatureWarningLowerLimit, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToInt16(ctx, 0x9019, p.ControllerInnerTemperatureUpperLimit, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToInt16(ctx, 0x901a, p.ControllerInnerTemperatureUpperLimitRecover, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToUint16(ctx, 0x901e, p.NightTimeThresholdVoltage, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegister(ctx, 0x901f, p.LightSignalStartupDelayTime)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToUint16(ctx, 0x9020, p.DayTimeThresholdVoltage, 100)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegister(ctx, 0x9021, p.LightSignalTurnOffDelayTime)
+	if err != nil {
+		return err
+	}
+	if p.BatteryRatedVoltageLevel != nil {
+		err = dev.mcWriteRegister(ctx, 0x9067, uint16(*p.BatteryRatedVoltageLevel))
+		if err != nil {
+			return err
+		}
+	}
+	err = dev.writeHoldingRegister(ctx, 0x906a, p.DefaultLoadOnOffInManualMode)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegister(ctx, 0x906b, p.EqualizeDuration)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegister(ctx, 0x906c, p.BoostDuration)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToUint16(ctx, 0x906d, p.BatteryDischarge, 100) // NOTE: possibly incorrect documentation (divisor)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromFloat64ToUint16(ctx, 0x906e, p.BatteryCharge, 100) // NOTE: possibly incorrect documentation (divisor)
+	if err != nil {
+		return err
+	}
+	if p.ChargingMode != nil {
+		err = dev.mcWriteRegister(ctx, 0x9070, uint16(*p.ChargingMode))
+		if err != nil {
+			return err
+		}
+	}
+	if p.LiBatteryProtectionAndOverTemperatureDropPower != nil {
+		err = dev.writeLiBatteryProtectionAndOverTemperatureDropPower(ctx, *p.LiBatteryProtectionAndOverTemperatureDropPower)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateTemperatureLimits merges the non-nil temperature limits of p into the current limits (0x9017-0x901a)
+// and checks the result with Parameters.Validate.
+func (dev *Dev) validateTemperatureLimits(ctx context.Context, p Parameters) error {
+	v, err := dev.mcReadRegisters(ctx, 0x9017, 4, HoldingRegister)
+	if err != nil {
+		return err
+	}
+
+	merged := func(value *float64, current uint16) *float64 {
+		if value != nil {
+			return value
+		}
+		f64 := float64(int16(current)) / 100
+		return &f64
+	}
+	violations := Parameters{
+		BatteryTemperatureWarningUpperLimit:         merged(p.BatteryTemperatureWarningUpperLimit, v[0]),
+		BatteryTemperatureWarningLowerLimit:         merged(p.BatteryTemperatureWarningLowerLimit, v[1]),
+		ControllerInnerTemperatureUpperLimit:        merged(p.ControllerInnerTemperatureUpperLimit, v[2]),
+		ControllerInnerTemperatureUpperLimitRecover: merged(p.ControllerInnerTemperatureUpperLimitRecover, v[3]),
+	}.Validate()
+	if len(violations) > 0 {
+		return &ValidationError{
+			Violations: violations,
+		}
+	}
+
+	return nil
+}
+
+// WriteBatteryVoltageSettings writes the battery voltage settings block (0x9000-0x900e) in a single
+// Write Multiple Registers request. The current block is read first and the non-nil fields of p
+// (BatteryType through DischargingLimitVoltage) are merged into it; all other fields of p are ignored.
+// The merged block is checked with Parameters.Validate before writing and a *ValidationError is returned
+// if any ordering rule is violated. If the controller rejects the block, a *BlockWriteRejectedError is returned.
+func (dev *Dev) WriteBatteryVoltageSettings(p Parameters) error {
+	return dev.WriteBatteryVoltageSettingsContext(context.Background(), p)
+}
+
+func (dev *Dev) WriteBatteryVoltageSettingsContext(ctx context.Context, p Parameters) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return err
+	}
+
+	return dev.writeBatteryVoltageSettings(ctx, p)
+}
+
+func (dev *Dev) writeBatteryVoltageSettings(ctx context.Context, p Parameters) error {
+	v, err := dev.mcReadRegisters(ctx, 0x9000, 15, HoldingRegister)
+	if err != nil {
+		return err
+	}
+
+	if p.BatteryType != nil {
+		v[0x00] = uint16(*p.BatteryType)
+	}
+	fields := []struct {
+		offset  int
+		value   *float64
+		divisor float64
+	}{
+		{0x01, p.BatteryCapacity, 1},
+		{0x02, p.TemperatureCompensationCoefficient, 100},
+		{0x03, p.OverVoltageDisconnectVoltage, 100},
+		{0x04, p.ChargingLimitVoltage, 100},
+		{0x05, p.OverVoltageReconnectVoltage, 100},
+		{0x06, p.EqualizeChargingVoltage, 100},
+		{0x07, p.BoostChargingVoltage, 100},
+		{0x08, p.FloatChargingVoltage, 100},
+		{0x09, p.BoostReconnectChargingVoltage, 100},
+		{0x0a, p.LowVoltageReconnectVoltage, 100},
+		{0x0b, p.UnderVoltageWarningRecoverVoltage, 100},
+		{0x0c, p.UnderVoltageWarningVoltage, 100},
+		{0x0d, p.LowVoltageDisconnectVoltage, 100},
+		{0x0e, p.DischargingLimitVoltage, 100},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		v[field.offset], err = encodeFloat64ToUint16(0x9000+uint16(field.offset), *field.value, field.divisor)
+		if err != nil {
+			return err
+		}
+	}
+
+	violations := decodeBatteryVoltageSettings(v).Validate()
+	if len(violations) > 0 {
+		return &ValidationError{
+			Violations: violations,
+		}
+	}
+
+	err = dev.mcWriteRegisters(ctx, 0x9000, v)
+	if err != nil {
+		if isModbusException(err) {
+			return &BlockWriteRejectedError{
+				Addr:     0x9000,
+				Quantity: uint16(len(v)),
+				Err:      err,
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// WriteLiBatteryProtectionAndOverTemperatureDropPower updates the known bits of 0x9107 from d.
+// The current register value is read first so that bits not modelled by d are preserved.
+func (dev *Dev) WriteLiBatteryProtectionAndOverTemperatureDropPower(d LiBatteryProtectionAndOverTemperatureDropPowerDetails) error {
+	return dev.WriteLiBatteryProtectionAndOverTemperatureDropPowerContext(context.Background(), d)
+}
+
+func (dev *Dev) WriteLiBatteryProtectionAndOverTemperatureDropPowerContext(ctx context.Context, d LiBatteryProtectionAndOverTemperatureDropPowerDetails) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return err
+	}
+
+	return dev.writeLiBatteryProtectionAndOverTemperatureDropPower(ctx, d)
+}
+
+func (dev *Dev) writeLiBatteryProtectionAndOverTemperatureDropPower(ctx context.Context, d LiBatteryProtectionAndOverTemperatureDropPowerDetails) error {
+	v, err := dev.mcReadRegister(ctx, 0x9107, HoldingRegister)
+	if err != nil {
+		return err
+	}
+
+	v2 := d.Apply(LiBatteryProtectionAndOverTemperatureDropPower(v))
+
+	return dev.mcWriteRegister(ctx, 0x9107, uint16(v2))
+}
+
 func (dev *Dev) ReadRealTimeData() (RealTimeData, error) {
-	dev.mutex.Lock()
+	return dev.ReadRealTimeDataContext(context.Background())
+}
+
+func (dev *Dev) ReadRealTimeDataContext(ctx context.Context) (RealTimeData, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return RealTimeData{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return RealTimeData{}, err
 	}
 
 	var r RealTimeData
 
-	r.PVArrayInputVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x3100, 100)
+	b1, err := dev.readRegisterBlock(ctx, 0x3100, 28, InputRegister)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	b2, err := dev.readRegisterBlock(ctx, 0x331a, 3, InputRegister)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.PVArrayInputVoltage, err = b1.uint16ToFloat64(ctx, 0x3100, 100)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.PVArrayInputCurrent, err = b1.uint16ToFloat64(ctx, 0x3101, 100)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.PVArrayInputPower, err = b1.uint32ToFloat64(ctx, 0x3102, 100)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.BatteryChargingVoltage, err = b1.uint16ToFloat64(ctx, 0x3104, 100)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.BatteryChargingCurrent, err = b1.uint16ToFloat64(ctx, 0x3105, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.PVArrayInputCurrent, err = dev.readInputRegisterFromUint16ToFloat64(0x3101, 100)
+	r.BatteryChargingPower, err = b1.uint32ToFloat64(ctx, 0x3106, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.PVArrayInputPower, err = dev.readInputRegisterFromUint32ToFloat64(0x3102, 100)
+	r.LoadVoltage, err = b1.uint16ToFloat64(ctx, 0x310c, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.LoadVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x310c, 100)
+	r.LoadCurrent, err = b1.uint16ToFloat64(ctx, 0x310d, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.LoadCurrent, err = dev.readInputRegisterFromUint16ToFloat64(0x310d, 100)
+	r.LoadPower, err = b1.uint32ToFloat64(ctx, 0x310e, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.LoadPower, err = dev.readInputRegisterFromUint32ToFloat64(0x310e, 100)
+	r.BatteryTemperature, err = b1.int16ToFloat64(ctx, 0x3110, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.BatteryTemperature, err = dev.readInputRegisterFromUint16ToFloat64(0x3110, 100)
+	r.DeviceTemperature, err = b1.int16ToFloat64(ctx, 0x3111, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.DeviceTemperature, err = dev.readInputRegisterFromUint16ToFloat64(0x3111, 100)
+	r.PowerComponentsTemperature, err = b1.int16ToFloat64(ctx, 0x3112, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.BatterySOC, err = dev.readInputRegisterFromUint16ToFloat64(0x311a, 1)
+	r.BatterySOC, err = b1.uint16ToFloat64(ctx, 0x311a, 1)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.BatteryVoltage, err = dev.readInputRegisterFromUint16ToFloat64(0x331a, 100)
+	r.RemoteBatteryTemperature, err = b1.int16ToFloat64(ctx, 0x311b, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
-	r.BatteryCurrent, err = dev.readInputRegisterFromUint32ToFloat64(0x331b, 100)
+	r.BatteryVoltage, err = b2.uint16ToFloat64(ctx, 0x331a, 100)
+	if err != nil {
+		return RealTimeData{}, err
+	}
+	r.BatteryCurrent, err = b2.int32ToFloat64(ctx, 0x331b, 100)
 	if err != nil {
 		return RealTimeData{}, err
 	}
@@ -285,26 +675,37 @@ func (dev *Dev) ReadRealTimeData() (RealTimeData, error) {
 }
 
 func (dev *Dev) ReadRealTimeStatus() (RealTimeStatus, error) {
-	dev.mutex.Lock()
+	return dev.ReadRealTimeStatusContext(context.Background())
+}
+
+func (dev *Dev) ReadRealTimeStatusContext(ctx context.Context) (RealTimeStatus, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return RealTimeStatus{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return RealTimeStatus{}, err
 	}
 
 	var r RealTimeStatus
 
-	r.OverTemperatureInsideTheDevice, err = dev.readDiscreteInput(0x2000)
+	r.OverTemperatureInsideTheDevice, err = dev.readDiscreteInput(ctx, 0x2000)
 	if err != nil {
 		return RealTimeStatus{}, err
 	}
-	r.Night, err = dev.readDiscreteInput(0x200c)
+	r.Night, err = dev.readDiscreteInput(ctx, 0x200c)
+	if err != nil {
+		return RealTimeStatus{}, err
+	}
+	r.ChargingDeviceOn, err = dev.readCoil(ctx, 0x0000)
 	if err != nil {
 		return RealTimeStatus{}, err
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x3200, modbus.INPUT_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x3200, InputRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return RealTimeStatus{}, err
@@ -316,7 +717,7 @@ func (dev *Dev) ReadRealTimeStatus() (RealTimeStatus, error) {
 		}
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x3201, modbus.INPUT_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x3201, InputRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return RealTimeStatus{}, err
@@ -328,7 +729,7 @@ func (dev *Dev) ReadRealTimeStatus() (RealTimeStatus, error) {
 		}
 	}
 	{
-		v, err := dev.mc.ReadRegister(0x3202, modbus.INPUT_REGISTER)
+		v, err := dev.mcReadRegister(ctx, 0x3202, InputRegister)
 		if err != nil {
 			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
 				return RealTimeStatus{}, err
@@ -344,78 +745,139 @@ func (dev *Dev) ReadRealTimeStatus() (RealTimeStatus, error) {
 }
 
 func (dev *Dev) ReadStatistics() (Statistics, error) {
-	dev.mutex.Lock()
+	return dev.ReadStatisticsContext(context.Background())
+}
+
+func (dev *Dev) ReadStatisticsContext(ctx context.Context) (Statistics, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return Statistics{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return Statistics{}, err
 	}
 
 	var r Statistics
 
-	r.MaximumArrayVoltageToday, err = dev.readInputRegisterFromUint16ToFloat64(0x3300, 100) // undocumented
+	b, err := dev.readRegisterBlock(ctx, 0x3300, 22, InputRegister)
+	if err != nil {
+		return Statistics{}, err
+	}
+	r.MaximumArrayVoltageToday, err = b.uint16ToFloat64(ctx, 0x3300, 100) // undocumented
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.MinimumArrayVoltageToday, err = dev.readInputRegisterFromUint16ToFloat64(0x3301, 100) // undocumented
+	r.MinimumArrayVoltageToday, err = b.uint16ToFloat64(ctx, 0x3301, 100) // undocumented
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.MaximumBatteryVoltageToday, err = dev.readInputRegisterFromUint16ToFloat64(0x3302, 100)
+	r.MaximumBatteryVoltageToday, err = b.uint16ToFloat64(ctx, 0x3302, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.MinimumBatteryVoltageToday, err = dev.readInputRegisterFromUint16ToFloat64(0x3303, 100)
+	r.MinimumBatteryVoltageToday, err = b.uint16ToFloat64(ctx, 0x3303, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.ConsumedEnergyToday, err = dev.readInputRegisterFromUint32ToFloat64(0x3304, 100)
+	r.ConsumedEnergyToday, err = b.uint32ToFloat64(ctx, 0x3304, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.ConsumedEnergyThisMonth, err = dev.readInputRegisterFromUint32ToFloat64(0x3306, 100)
+	r.ConsumedEnergyThisMonth, err = b.uint32ToFloat64(ctx, 0x3306, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.ConsumedEnergyThisYear, err = dev.readInputRegisterFromUint32ToFloat64(0x3308, 100)
+	r.ConsumedEnergyThisYear, err = b.uint32ToFloat64(ctx, 0x3308, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.TotalConsumedEnergy, err = dev.readInputRegisterFromUint32ToFloat64(0x330a, 100)
+	r.TotalConsumedEnergy, err = b.uint32ToFloat64(ctx, 0x330a, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.GeneratedEnergyToday, err = dev.readInputRegisterFromUint32ToFloat64(0x330c, 100)
+	r.GeneratedEnergyToday, err = b.uint32ToFloat64(ctx, 0x330c, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.GeneratedEnergyThisMonth, err = dev.readInputRegisterFromUint32ToFloat64(0x330e, 100)
+	r.GeneratedEnergyThisMonth, err = b.uint32ToFloat64(ctx, 0x330e, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.GeneratedEnergyThisYear, err = dev.readInputRegisterFromUint32ToFloat64(0x3310, 100)
+	r.GeneratedEnergyThisYear, err = b.uint32ToFloat64(ctx, 0x3310, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
-	r.TotalGeneratedEnergy, err = dev.readInputRegisterFromUint32ToFloat64(0x3312, 100)
+	r.TotalGeneratedEnergy, err = b.uint32ToFloat64(ctx, 0x3312, 100)
 	if err != nil {
 		return Statistics{}, err
 	}
+	r.CarbonDioxideReduction, err = b.uint32ToFloat64(ctx, 0x3314, 100)
+	if err != nil {
+		return Statistics{}, err
+	}
+
+	return r, nil
+}
+
+// ReadDeviceInfo identifies the controller using Modbus Read Device Identification.
+//
+// If the transport or controller does not support the function, an empty DeviceInfo is returned. A controller
+// that does not answer within the timeout is treated as not supporting it. The request is made once, without
+// retries, and the result is cached, so later calls perform no Modbus transaction.
+func (dev *Dev) ReadDeviceInfo() (DeviceInfo, error) {
+	return dev.ReadDeviceInfoContext(context.Background())
+}
+
+func (dev *Dev) ReadDeviceInfoContext(ctx context.Context) (DeviceInfo, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	defer dev.mutex.Unlock()
+
+	if dev.deviceInfo != nil {
+		return *dev.deviceInfo, nil
+	}
+
+	err = dev.requestSetup()
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+
+	var r DeviceInfo
+	v, err := dev.mcReadDeviceIdentification(ctx)
+	if err != nil {
+		if !errors.Is(err, modbus.ErrIllegalFunction) && !errors.Is(err, modbus.ErrIllegalDataAddress) && !errors.Is(err, modbus.ErrRequestTimedOut) {
+			return DeviceInfo{}, err
+		}
+	} else {
+		r = decodeDeviceInfo(v)
+	}
 
+	dev.deviceInfo = &r
 	return r, nil
 }
 
 func (dev *Dev) ReadRealTimeClock() (RTCData, error) {
-	dev.mutex.Lock()
+	return dev.ReadRealTimeClockContext(context.Background())
+}
+
+func (dev *Dev) ReadRealTimeClockContext(ctx context.Context) (RTCData, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return RTCData{}, err
+	}
 	defer dev.mutex.Unlock()
 
-	err := dev.requestSetup()
+	err = dev.requestSetup()
 	if err != nil {
 		return RTCData{}, err
 	}
 
-	v, err := dev.mc.ReadRegisters(0x9013, 3, modbus.HOLDING_REGISTER)
+	v, err := dev.mcReadRegisters(ctx, 0x9013, 3, HoldingRegister)
 	if err != nil {
 		return RTCData{}, err
 	}
@@ -433,7 +895,14 @@ func (dev *Dev) ReadRealTimeClock() (RTCData, error) {
 }
 
 func (dev *Dev) SetRealTimeClock(r RTCData) error {
-	dev.mutex.Lock()
+	return dev.SetRealTimeClockContext(context.Background(), r)
+}
+
+func (dev *Dev) SetRealTimeClockContext(ctx context.Context, r RTCData) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
 	defer dev.mutex.Unlock()
 
 	v := make([]uint16, 3)
@@ -441,7 +910,258 @@ func (dev *Dev) SetRealTimeClock(r RTCData) error {
 	v[1] = binary.BigEndian.Uint16([]byte{r.Day, r.Hour})
 	v[2] = binary.BigEndian.Uint16([]byte{r.Year, r.Month})
 
-	err := dev.mc.WriteRegisters(0x9013, v)
+	err = dev.mcWriteRegisters(ctx, 0x9013, v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dev *Dev) ReadChargingDevice() (bool, error) {
+	return dev.ReadChargingDeviceContext(context.Background())
+}
+
+func (dev *Dev) ReadChargingDeviceContext(ctx context.Context) (bool, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return false, err
+	}
+
+	return dev.mcReadCoil(ctx, 0x0000)
+}
+
+// SetChargingDevice turns charging on or off.
+func (dev *Dev) SetChargingDevice(on bool) error {
+	return dev.SetChargingDeviceContext(context.Background(), on)
+}
+
+func (dev *Dev) SetChargingDeviceContext(ctx context.Context, on bool) error {
+	return dev.setCoil(ctx, 0x0000, on)
+}
+
+// RestoreDefaults restores the system default settings.
+func (dev *Dev) RestoreDefaults() error {
+	return dev.RestoreDefaultsContext(context.Background())
+}
+
+func (dev *Dev) RestoreDefaultsContext(ctx context.Context) error {
+	return dev.setCoil(ctx, 0x0013, true)
+}
+
+// ClearStatistics clears the generated energy statistics.
+func (dev *Dev) ClearStatistics() error {
+	return dev.ClearStatisticsContext(context.Background())
+}
+
+func (dev *Dev) ClearStatisticsContext(ctx context.Context) error {
+	return dev.setCoil(ctx, 0x0014, true)
+}
+
+func (dev *Dev) ReadLoadControl() (LoadControl, error) {
+	return dev.ReadLoadControlContext(context.Background())
+}
+
+func (dev *Dev) ReadLoadControlContext(ctx context.Context) (LoadControl, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return LoadControl{}, err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return LoadControl{}, err
+	}
+
+	var r LoadControl
+
+	r.ManualLoadControl, err = dev.readCoil(ctx, 0x0002)
+	if err != nil {
+		return LoadControl{}, err
+	}
+	r.DefaultLoadState, err = dev.readCoil(ctx, 0x0003)
+	if err != nil {
+		return LoadControl{}, err
+	}
+	r.LoadTestMode, err = dev.readCoil(ctx, 0x0005)
+	if err != nil {
+		return LoadControl{}, err
+	}
+	r.ForceLoad, err = dev.readCoil(ctx, 0x0006)
+	if err != nil {
+		return LoadControl{}, err
+	}
+
+	return r, nil
+}
+
+// SetManualLoadControl turns the load on or off when the load controlling mode is manual.
+func (dev *Dev) SetManualLoadControl(on bool) error {
+	return dev.SetManualLoadControlContext(context.Background(), on)
+}
+
+func (dev *Dev) SetManualLoadControlContext(ctx context.Context, on bool) error {
+	return dev.setCoil(ctx, 0x0002, on)
+}
+
+// SetDefaultLoadState sets the load state applied in manual mode after power-up.
+func (dev *Dev) SetDefaultLoadState(on bool) error {
+	return dev.SetDefaultLoadStateContext(context.Background(), on)
+}
+
+func (dev *Dev) SetDefaultLoadStateContext(ctx context.Context, on bool) error {
+	return dev.setCoil(ctx, 0x0003, on)
+}
+
+// SetLoadTestMode enables or disables load test mode.
+func (dev *Dev) SetLoadTestMode(enabled bool) error {
+	return dev.SetLoadTestModeContext(context.Background(), enabled)
+}
+
+func (dev *Dev) SetLoadTestModeContext(ctx context.Context, enabled bool) error {
+	return dev.setCoil(ctx, 0x0005, enabled)
+}
+
+// ForceLoad turns the load on or off temporarily, e.g. while in load test mode.
+func (dev *Dev) ForceLoad(on bool) error {
+	return dev.ForceLoadContext(context.Background(), on)
+}
+
+func (dev *Dev) ForceLoadContext(ctx context.Context, on bool) error {
+	return dev.setCoil(ctx, 0x0006, on)
+}
+
+func (dev *Dev) setCoil(ctx context.Context, addr uint16, v bool) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return err
+	}
+
+	return dev.mcWriteCoil(ctx, addr, v)
+}
+
+func (dev *Dev) ReadLoadControlSettings() (LoadControlSettings, error) {
+	return dev.ReadLoadControlSettingsContext(context.Background())
+}
+
+func (dev *Dev) ReadLoadControlSettingsContext(ctx context.Context) (LoadControlSettings, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+
+	var r LoadControlSettings
+
+	{
+		v, err := dev.mcReadRegister(ctx, 0x903d, HoldingRegister)
+		if err != nil {
+			if !errors.Is(err, modbus.ErrIllegalDataAddress) {
+				return LoadControlSettings{}, err
+			}
+		} else {
+			v2 := LoadControllingMode(v)
+			r.LoadControllingMode = &v2
+		}
+	}
+	r.WorkingTimeLength1, err = dev.readHoldingRegisterAsHourMinute(ctx, 0x903e)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.WorkingTimeLength2, err = dev.readHoldingRegisterAsHourMinute(ctx, 0x903f)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.TurnOnTiming1, err = dev.readHoldingRegistersAsTimeOfDay(ctx, 0x9042)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.TurnOffTiming1, err = dev.readHoldingRegistersAsTimeOfDay(ctx, 0x9045)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.TurnOnTiming2, err = dev.readHoldingRegistersAsTimeOfDay(ctx, 0x9048)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.TurnOffTiming2, err = dev.readHoldingRegistersAsTimeOfDay(ctx, 0x904b)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+	r.LengthOfNight, err = dev.readHoldingRegisterAsHourMinute(ctx, 0x9065)
+	if err != nil {
+		return LoadControlSettings{}, err
+	}
+
+	return r, nil
+}
+
+// WriteLoadControlSettings writes the non-nil fields of s to the corresponding holding registers.
+func (dev *Dev) WriteLoadControlSettings(s LoadControlSettings) error {
+	return dev.WriteLoadControlSettingsContext(context.Background(), s)
+}
+
+func (dev *Dev) WriteLoadControlSettingsContext(ctx context.Context, s LoadControlSettings) error {
+	err := dev.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer dev.mutex.Unlock()
+
+	err = dev.requestSetup()
+	if err != nil {
+		return err
+	}
+
+	if s.LoadControllingMode != nil {
+		err = dev.mcWriteRegister(ctx, 0x903d, uint16(*s.LoadControllingMode))
+		if err != nil {
+			return err
+		}
+	}
+	err = dev.writeHoldingRegisterFromHourMinute(ctx, 0x903e, s.WorkingTimeLength1)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromHourMinute(ctx, 0x903f, s.WorkingTimeLength2)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegistersFromTimeOfDay(ctx, 0x9042, s.TurnOnTiming1)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegistersFromTimeOfDay(ctx, 0x9045, s.TurnOffTiming1)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegistersFromTimeOfDay(ctx, 0x9048, s.TurnOnTiming2)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegistersFromTimeOfDay(ctx, 0x904b, s.TurnOffTiming2)
+	if err != nil {
+		return err
+	}
+	err = dev.writeHoldingRegisterFromHourMinute(ctx, 0x9065, s.LengthOfNight)
 	if err != nil {
 		return err
 	}
@@ -449,8 +1169,8 @@ func (dev *Dev) SetRealTimeClock(r RTCData) error {
 	return nil
 }
 
-func (dev *Dev) readInputRegisterFromUint16ToFloat64(addr uint16, divisor float64) (*float64, error) {
-	v, err := dev.mc.ReadRegister(addr, modbus.INPUT_REGISTER)
+func (dev *Dev) readInputRegisterFromUint16ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := dev.mcReadRegister(ctx, addr, InputRegister)
 	if err != nil {
 		if errors.Is(err, modbus.ErrIllegalDataAddress) {
 			return nil, nil
@@ -462,8 +1182,21 @@ func (dev *Dev) readInputRegisterFromUint16ToFloat64(addr uint16, divisor float6
 	return &f64, nil
 }
 
-func (dev *Dev) readInputRegisterFromUint32ToFloat64(addr uint16, divisor float64) (*float64, error) {
-	v, err := dev.mc.ReadRegisters(addr, 2, modbus.INPUT_REGISTER)
+// decodeUint32 decodes a low word first unsigned 32-bit value.
+func decodeUint32(v []uint16) uint32 {
+	var b []byte
+	b = binary.BigEndian.AppendUint16(b, v[1])
+	b = binary.BigEndian.AppendUint16(b, v[0])
+	return binary.BigEndian.Uint32(b)
+}
+
+// decodeInt32 decodes a low word first signed 32-bit value.
+func decodeInt32(v []uint16) int32 {
+	return int32(decodeUint32(v))
+}
+
+func (dev *Dev) readHoldingRegister(ctx context.Context, addr uint16) (*uint16, error) {
+	v, err := dev.mcReadRegister(ctx, addr, HoldingRegister)
 	if err != nil {
 		if errors.Is(err, modbus.ErrIllegalDataAddress) {
 			return nil, nil
@@ -471,15 +1204,24 @@ func (dev *Dev) readInputRegisterFromUint32ToFloat64(addr uint16, divisor float6
 			return nil, err
 		}
 	}
-	var b []byte
-	b = binary.BigEndian.AppendUint16(b, v[1])
-	b = binary.BigEndian.AppendUint16(b, v[0])
-	f64 := float64(int32(binary.BigEndian.Uint32(b))) / divisor
+	return &v, nil
+}
+
+func (dev *Dev) readHoldingRegisterFromUint16ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := dev.mcReadRegister(ctx, addr, HoldingRegister)
+	if err != nil {
+		if errors.Is(err, modbus.ErrIllegalDataAddress) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+	}
+	f64 := float64(v) / divisor
 	return &f64, nil
 }
 
-func (dev *Dev) readHoldingRegister(addr uint16) (*uint16, error) {
-	v, err := dev.mc.ReadRegister(addr, modbus.HOLDING_REGISTER)
+func (dev *Dev) readHoldingRegisterAsHourMinute(ctx context.Context, addr uint16) (*time.Duration, error) {
+	v, err := dev.mcReadRegister(ctx, addr, HoldingRegister)
 	if err != nil {
 		if errors.Is(err, modbus.ErrIllegalDataAddress) {
 			return nil, nil
@@ -487,11 +1229,13 @@ func (dev *Dev) readHoldingRegister(addr uint16) (*uint16, error) {
 			return nil, err
 		}
 	}
-	return &v, nil
+	d := decodeHourMinute(v)
+	return &d, nil
 }
 
-func (dev *Dev) readHoldingRegisterFromUint16ToFloat64(addr uint16, divisor float64) (*float64, error) {
-	v, err := dev.mc.ReadRegister(addr, modbus.HOLDING_REGISTER)
+// readHoldingRegistersAsTimeOfDay reads a second, minute, hour register triplet.
+func (dev *Dev) readHoldingRegistersAsTimeOfDay(ctx context.Context, addr uint16) (*TimeOfDay, error) {
+	v, err := dev.mcReadRegisters(ctx, addr, 3, HoldingRegister)
 	if err != nil {
 		if errors.Is(err, modbus.ErrIllegalDataAddress) {
 			return nil, nil
@@ -499,12 +1243,132 @@ func (dev *Dev) readHoldingRegisterFromUint16ToFloat64(addr uint16, divisor floa
 			return nil, err
 		}
 	}
-	f64 := float64(v) / divisor
+	return &TimeOfDay{
+		Second: uint8(v[0]),
+		Minute: uint8(v[1]),
+		Hour:   uint8(v[2]),
+	}, nil
+}
+
+func (dev *Dev) writeHoldingRegister(ctx context.Context, addr uint16, v *uint16) error {
+	if v == nil {
+		return nil
+	}
+	return dev.mcWriteRegister(ctx, addr, *v)
+}
+
+func (dev *Dev) writeHoldingRegisterFromFloat64ToUint16(ctx context.Context, addr uint16, v *float64, divisor float64) error {
+	if v == nil {
+		return nil
+	}
+	u16, err := encodeFloat64ToUint16(addr, *v, divisor)
+	if err != nil {
+		return err
+	}
+	return dev.mcWriteRegister(ctx, addr, u16)
+}
+
+func encodeFloat64ToInt16(addr uint16, v float64, divisor float64) (uint16, error) {
+	f64 := math.Round(v * divisor)
+	if (f64 < math.MinInt16) || (f64 > math.MaxInt16) {
+		return 0, fmt.Errorf("value out of range for register 0x%04x: %v", addr, v)
+	}
+	return uint16(int16(f64)), nil
+}
+
+func decodeBatteryVoltageSettings(v []uint16) Parameters {
+	f64 := func(v uint16, divisor float64) *float64 {
+		f64 := float64(v) / divisor
+		return &f64
+	}
+	batteryType := BatteryType(v[0x00])
+	return Parameters{
+		BatteryType:                        &batteryType,
+		BatteryCapacity:                    f64(v[0x01], 1),
+		TemperatureCompensationCoefficient: f64(v[0x02], 100),
+		OverVoltageDisconnectVoltage:       f64(v[0x03], 100),
+		ChargingLimitVoltage:               f64(v[0x04], 100),
+		OverVoltageReconnectVoltage:        f64(v[0x05], 100),
+		EqualizeChargingVoltage:            f64(v[0x06], 100),
+		BoostChargingVoltage:               f64(v[0x07], 100),
+		FloatChargingVoltage:               f64(v[0x08], 100),
+		BoostReconnectChargingVoltage:      f64(v[0x09], 100),
+		LowVoltageReconnectVoltage:         f64(v[0x0a], 100),
+		UnderVoltageWarningRecoverVoltage:  f64(v[0x0b], 100),
+		UnderVoltageWarningVoltage:         f64(v[0x0c], 100),
+		LowVoltageDisconnectVoltage:        f64(v[0x0d], 100),
+		DischargingLimitVoltage:            f64(v[0x0e], 100),
+	}
+}
+
+func (dev *Dev) writeHoldingRegisterFromFloat64ToInt16(ctx context.Context, addr uint16, v *float64, divisor float64) error {
+	if v == nil {
+		return nil
+	}
+	u16, err := encodeFloat64ToInt16(addr, *v, divisor)
+	if err != nil {
+		return err
+	}
+	return dev.mcWriteRegister(ctx, addr, u16)
+}
+
+func (dev *Dev) writeHoldingRegisterFromHourMinute(ctx context.Context, addr uint16, d *time.Duration) error {
+	if d == nil {
+		return nil
+	}
+	v, err := encodeHourMinute(*d)
+	if err != nil {
+		return err
+	}
+	return dev.mcWriteRegister(ctx, addr, v)
+}
+
+// writeHoldingRegistersFromTimeOfDay writes a second, minute, hour register triplet.
+func (dev *Dev) writeHoldingRegistersFromTimeOfDay(ctx context.Context, addr uint16, t *TimeOfDay) error {
+	if t == nil {
+		return nil
+	}
+	if (t.Hour > 23) || (t.Minute > 59) || (t.Second > 59) {
+		return fmt.Errorf("invalid time of day: %s", t)
+	}
+	return dev.mcWriteRegisters(ctx, addr, []uint16{uint16(t.Second), uint16(t.Minute), uint16(t.Hour)})
+}
+
+func encodeFloat64ToUint16(addr uint16, v float64, divisor float64) (uint16, error) {
+	f64 := math.Round(v * divisor)
+	if (f64 < 0) || (f64 > math.MaxUint16) {
+		return 0, fmt.Errorf("value out of range for register 0x%04x: %v", addr, v)
+	}
+	return uint16(f64), nil
+}
+
+func (dev *Dev) readHoldingRegisterFromInt16ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := dev.mcReadRegister(ctx, addr, HoldingRegister)
+	if err != nil {
+		if errors.Is(err, modbus.ErrIllegalDataAddress) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+	}
+	f64 := float64(int16(v)) / divisor
 	return &f64, nil
 }
 
-func (dev *Dev) readDiscreteInput(addr uint16) (*bool, error) {
-	v, err := dev.mc.ReadDiscreteInput(addr)
+func (dev *Dev) readDiscreteInput(ctx context.Context, addr uint16) (*bool, error) {
+	v, err := dev.mcReadDiscreteInput(ctx, addr)
+	if err != nil {
+		if errors.Is(err, modbus.ErrIllegalDataAddress) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+	}
+	return &v, nil
+}
+
+func (dev *Dev) readCoil(ctx context.Context, addr uint16) (*bool, error) {
+	v, err := dev.mcReadCoil(ctx, addr)
 	if err != nil {
 		if errors.Is(err, modbus.ErrIllegalDataAddress) {
 			return nil, nil
@@ -0,0 +1,30 @@
+package epsolar
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/simonvetter/modbus"
+)
+
+// BlockWriteRejectedError is returned when the controller rejects a multi-register block write.
+type BlockWriteRejectedError struct {
+	Addr     uint16
+	Quantity uint16
+	Err      error
+}
+
+func (e *BlockWriteRejectedError) Error() string {
+	return fmt.Sprintf("block write of %d registers at 0x%04x rejected: %v", e.Quantity, e.Addr, e.Err)
+}
+
+func (e *BlockWriteRejectedError) Unwrap() error {
+	return e.Err
+}
+
+func isModbusException(err error) bool {
+	return errors.Is(err, modbus.ErrIllegalFunction) ||
+		errors.Is(err, modbus.ErrIllegalDataAddress) ||
+		errors.Is(err, modbus.ErrIllegalDataValue) ||
+		errors.Is(err, modbus.ErrServerDeviceFailure)
+}
@@ -0,0 +1,96 @@
+package epsolar
+
+import (
+	"fmt"
+	"time"
+)
+
+type LoadControl struct {
+	ManualLoadControl *bool // on
+	DefaultLoadState  *bool // on
+	LoadTestMode      *bool // enabled
+	ForceLoad         *bool // on
+}
+
+// ---
+
+type LoadControlSettings struct {
+	LoadControllingMode *LoadControllingMode
+	WorkingTimeLength1  *time.Duration // light on + timer, resolution 1 minute
+	WorkingTimeLength2  *time.Duration // light on + timer, resolution 1 minute
+	TurnOnTiming1       *TimeOfDay     // time control
+	TurnOffTiming1      *TimeOfDay     // time control
+	TurnOnTiming2       *TimeOfDay     // time control
+	TurnOffTiming2      *TimeOfDay     // time control
+	LengthOfNight       *time.Duration // resolution 1 minute
+}
+
+// ---
+
+type LoadControllingMode uint16
+
+const (
+	LoadControllingModeManual LoadControllingMode = iota
+	LoadControllingModeLightOnOff
+	LoadControllingModeLightOnTimer
+	LoadControllingModeTimeControl
+)
+
+func (v LoadControllingMode) String() string {
+	switch v {
+	case LoadControllingModeManual:
+		return "Manual Control"
+	case LoadControllingModeLightOnOff:
+		return "Light ON/OFF"
+	case LoadControllingModeLightOnTimer:
+		return "Light ON + Timer"
+	case LoadControllingModeTimeControl:
+		return "Time Control"
+	default:
+		return fmt.Sprintf("0x%02x", uint8(v))
+	}
+}
+
+// ---
+
+type TimeOfDay struct {
+	Hour   uint8
+	Minute uint8
+	Second uint8
+}
+
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	for _, layout := range []string{time.TimeOnly, "15:04"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return TimeOfDay{
+				Hour:   uint8(t.Hour()),
+				Minute: uint8(t.Minute()),
+				Second: uint8(t.Second()),
+			}, nil
+		}
+	}
+	return TimeOfDay{}, fmt.Errorf("invalid time of day: %s", s)
+}
+
+func (v TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", v.Hour, v.Minute, v.Second)
+}
+
+// ---
+
+func decodeHourMinute(v uint16) time.Duration {
+	return time.Duration(getBits(v, 8, 0xff))*time.Hour + time.Duration(getBits(v, 0, 0xff))*time.Minute
+}
+
+func encodeHourMinute(d time.Duration) (uint16, error) {
+	if (d < 0) || (d >= 256*time.Hour) {
+		return 0, fmt.Errorf("duration out of range: %v", d)
+	}
+	if d%time.Minute != 0 {
+		return 0, fmt.Errorf("duration not a whole number of minutes: %v", d)
+	}
+	hours := uint16(d / time.Hour)
+	minutes := uint16((d % time.Hour) / time.Minute)
+	return hours<<8 | minutes, nil
+}
@@ -0,0 +1,90 @@
+package epsolar
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+	"weak"
+)
+
+// busState is shared by all Devs using the same mutex. It is only accessed while the mutex is held.
+type busState struct {
+	interFrameDelay time.Duration
+	lastTransaction time.Time
+}
+
+// busStates maps each mutex passed to New to its busState. Entries are removed once the mutex, and therefore
+// every Dev using it, has been garbage collected.
+var (
+	busStatesMutex sync.Mutex
+	busStates      = make(map[weak.Pointer[sync.Mutex]]*busState)
+)
+
+func busStateFor(mutex *sync.Mutex) *busState {
+	busStatesMutex.Lock()
+	defer busStatesMutex.Unlock()
+
+	key := weak.Make(mutex)
+	state, ok := busStates[key]
+	if !ok {
+		state = &busState{}
+		busStates[key] = state
+		runtime.AddCleanup(mutex, func(key weak.Pointer[sync.Mutex]) {
+			busStatesMutex.Lock()
+			defer busStatesMutex.Unlock()
+
+			delete(busStates, key)
+		}, key)
+	}
+	return state
+}
+
+// SetInterFrameDelay sets the minimum gap between the end of the previous Modbus transaction on the bus and
+// the start of the next one. The delay applies to all Devs sharing the same mutex, and replaces any delay set
+// through another of them.
+func (dev *Dev) SetInterFrameDelay(delay time.Duration) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+
+	dev.bus.interFrameDelay = delay
+}
+
+// pace waits until the inter-frame delay has elapsed since the last transaction on the bus, giving up if ctx
+// is done first.
+func (dev *Dev) pace(ctx context.Context) error {
+	if dev.bus.interFrameDelay <= 0 {
+		return nil
+	}
+	wait := time.Until(dev.bus.lastTransaction.Add(dev.bus.interFrameDelay))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transact performs a single Modbus transaction, honouring the inter-frame delay and retry policy.
+func (dev *Dev) transact(ctx context.Context, fn func() error) error {
+	return dev.transactWithRetryPolicy(ctx, dev.retryPolicy, fn)
+}
+
+// transactWithRetryPolicy is transact with a retry policy other than the Dev's.
+func (dev *Dev) transactWithRetryPolicy(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	return policy.do(ctx, func() error {
+		err := dev.pace(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			dev.bus.lastTransaction = time.Now()
+		}()
+		return fn()
+	})
+}
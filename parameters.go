@@ -18,6 +18,15 @@ type Parameters struct {
 	UnderVoltageWarningVoltage                     *float64 // V
 	LowVoltageDisconnectVoltage                    *float64 // V
 	DischargingLimitVoltage                        *float64 // V
+	EqualizeChargingCycle                          *uint16  // days
+	BatteryTemperatureWarningUpperLimit            *float64 // C
+	BatteryTemperatureWarningLowerLimit            *float64 // C
+	ControllerInnerTemperatureUpperLimit           *float64 // C
+	ControllerInnerTemperatureUpperLimitRecover    *float64 // C
+	NightTimeThresholdVoltage                      *float64 // V
+	LightSignalStartupDelayTime                    *uint16  // minutes
+	DayTimeThresholdVoltage                        *float64 // V
+	LightSignalTurnOffDelayTime                    *uint16  // minutes
 	BatteryRatedVoltageLevel                       *BatteryRatedVoltageLevel
 	DefaultLoadOnOffInManualMode                   *uint16
 	EqualizeDuration                               *uint16  // minutes
@@ -28,6 +37,31 @@ type Parameters struct {
 	LiBatteryProtectionAndOverTemperatureDropPower *LiBatteryProtectionAndOverTemperatureDropPowerDetails
 }
 
+func (p Parameters) hasBatteryVoltageSettings() bool {
+	return (p.BatteryType != nil) ||
+		(p.BatteryCapacity != nil) ||
+		(p.TemperatureCompensationCoefficient != nil) ||
+		(p.OverVoltageDisconnectVoltage != nil) ||
+		(p.ChargingLimitVoltage != nil) ||
+		(p.OverVoltageReconnectVoltage != nil) ||
+		(p.EqualizeChargingVoltage != nil) ||
+		(p.BoostChargingVoltage != nil) ||
+		(p.FloatChargingVoltage != nil) ||
+		(p.BoostReconnectChargingVoltage != nil) ||
+		(p.LowVoltageReconnectVoltage != nil) ||
+		(p.UnderVoltageWarningRecoverVoltage != nil) ||
+		(p.UnderVoltageWarningVoltage != nil) ||
+		(p.LowVoltageDisconnectVoltage != nil) ||
+		(p.DischargingLimitVoltage != nil)
+}
+
+func (p Parameters) hasTemperatureLimits() bool {
+	return (p.BatteryTemperatureWarningUpperLimit != nil) ||
+		(p.BatteryTemperatureWarningLowerLimit != nil) ||
+		(p.ControllerInnerTemperatureUpperLimit != nil) ||
+		(p.ControllerInnerTemperatureUpperLimitRecover != nil)
+}
+
 // ---
 
 type BatteryType uint16
@@ -164,3 +198,17 @@ type LiBatteryProtectionAndOverTemperatureDropPowerDetails struct {
 	LowTemperatureProtectionForDischarging bool
 	OverTemperatureDropPower               bool
 }
+
+// Apply returns v with the known bits replaced by the values in d. All other bits of v are preserved.
+func (d LiBatteryProtectionAndOverTemperatureDropPowerDetails) Apply(v LiBatteryProtectionAndOverTemperatureDropPower) LiBatteryProtectionAndOverTemperatureDropPower {
+	u16 := uint16(v)
+	u16 = setBit(u16, 8, d.LowTemperatureProtectionForCharging)
+	u16 = setBit(u16, 9, d.LowTemperatureProtectionForDischarging)
+	u16 = setBit(u16, 11, d.OverTemperatureDropPower)
+	return LiBatteryProtectionAndOverTemperatureDropPower(u16)
+}
+
+// Value encodes d back to the raw register value, preserving the unknown bits of Raw.
+func (d LiBatteryProtectionAndOverTemperatureDropPowerDetails) Value() LiBatteryProtectionAndOverTemperatureDropPower {
+	return d.Apply(LiBatteryProtectionAndOverTemperatureDropPower(d.Raw))
+}
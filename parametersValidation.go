@@ -0,0 +1,101 @@
+package epsolar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relation is the required relation between two parameters.
+type Relation string
+
+const (
+	RelationGreaterThan        Relation = ">"
+	RelationGreaterThanOrEqual Relation = ">="
+)
+
+// Violation describes a pair of parameters that does not satisfy a documented ordering rule.
+type Violation struct {
+	Field      string
+	Value      float64
+	Relation   Relation
+	Other      string
+	OtherValue float64
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%v) must be %s %s (%v)", v.Field, v.Value, v.Relation, v.Other, v.OtherValue)
+}
+
+// ValidationError is returned when parameters fail validation.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var parts []string
+	for _, violation := range e.Violations {
+		parts = append(parts, violation.String())
+	}
+	return "invalid parameters: " + strings.Join(parts, "; ")
+}
+
+type parameterRule struct {
+	field      string
+	value      func(p Parameters) *float64
+	relation   Relation
+	other      string
+	otherValue func(p Parameters) *float64
+}
+
+var parameterRules = []parameterRule{
+	// Over Voltage Disconnect Voltage > Charging Limit Voltage >= Equalize Charging Voltage >= Boost Charging Voltage >= Float Charging Voltage > Boost Reconnect Charging Voltage
+	{"OverVoltageDisconnectVoltage", func(p Parameters) *float64 { return p.OverVoltageDisconnectVoltage }, RelationGreaterThan, "ChargingLimitVoltage", func(p Parameters) *float64 { return p.ChargingLimitVoltage }},
+	{"ChargingLimitVoltage", func(p Parameters) *float64 { return p.ChargingLimitVoltage }, RelationGreaterThanOrEqual, "EqualizeChargingVoltage", func(p Parameters) *float64 { return p.EqualizeChargingVoltage }},
+	{"EqualizeChargingVoltage", func(p Parameters) *float64 { return p.EqualizeChargingVoltage }, RelationGreaterThanOrEqual, "BoostChargingVoltage", func(p Parameters) *float64 { return p.BoostChargingVoltage }},
+	{"BoostChargingVoltage", func(p Parameters) *float64 { return p.BoostChargingVoltage }, RelationGreaterThanOrEqual, "FloatChargingVoltage", func(p Parameters) *float64 { return p.FloatChargingVoltage }},
+	{"FloatChargingVoltage", func(p Parameters) *float64 { return p.FloatChargingVoltage }, RelationGreaterThan, "BoostReconnectChargingVoltage", func(p Parameters) *float64 { return p.BoostReconnectChargingVoltage }},
+	// Over Voltage Disconnect Voltage > Over Voltage Reconnect Voltage
+	{"OverVoltageDisconnectVoltage", func(p Parameters) *float64 { return p.OverVoltageDisconnectVoltage }, RelationGreaterThan, "OverVoltageReconnectVoltage", func(p Parameters) *float64 { return p.OverVoltageReconnectVoltage }},
+	// Boost Reconnect Charging Voltage > Low Voltage Reconnect Voltage
+	{"BoostReconnectChargingVoltage", func(p Parameters) *float64 { return p.BoostReconnectChargingVoltage }, RelationGreaterThan, "LowVoltageReconnectVoltage", func(p Parameters) *float64 { return p.LowVoltageReconnectVoltage }},
+	// Low Voltage Reconnect Voltage > Low Voltage Disconnect Voltage >= Discharging Limit Voltage
+	{"LowVoltageReconnectVoltage", func(p Parameters) *float64 { return p.LowVoltageReconnectVoltage }, RelationGreaterThan, "LowVoltageDisconnectVoltage", func(p Parameters) *float64 { return p.LowVoltageDisconnectVoltage }},
+	{"LowVoltageDisconnectVoltage", func(p Parameters) *float64 { return p.LowVoltageDisconnectVoltage }, RelationGreaterThanOrEqual, "DischargingLimitVoltage", func(p Parameters) *float64 { return p.DischargingLimitVoltage }},
+	// Under Voltage Warning Recover Voltage > Under Voltage Warning Voltage >= Discharging Limit Voltage
+	{"UnderVoltageWarningRecoverVoltage", func(p Parameters) *float64 { return p.UnderVoltageWarningRecoverVoltage }, RelationGreaterThan, "UnderVoltageWarningVoltage", func(p Parameters) *float64 { return p.UnderVoltageWarningVoltage }},
+	{"UnderVoltageWarningVoltage", func(p Parameters) *float64 { return p.UnderVoltageWarningVoltage }, RelationGreaterThanOrEqual, "DischargingLimitVoltage", func(p Parameters) *float64 { return p.DischargingLimitVoltage }},
+	// Battery Temperature Warning Upper Limit > Battery Temperature Warning Lower Limit
+	{"BatteryTemperatureWarningUpperLimit", func(p Parameters) *float64 { return p.BatteryTemperatureWarningUpperLimit }, RelationGreaterThan, "BatteryTemperatureWarningLowerLimit", func(p Parameters) *float64 { return p.BatteryTemperatureWarningLowerLimit }},
+	// Controller Inner Temperature Upper Limit > Controller Inner Temperature Upper Limit Recover
+	{"ControllerInnerTemperatureUpperLimit", func(p Parameters) *float64 { return p.ControllerInnerTemperatureUpperLimit }, RelationGreaterThan, "ControllerInnerTemperatureUpperLimitRecover", func(p Parameters) *float64 { return p.ControllerInnerTemperatureUpperLimitRecover }},
+}
+
+// Validate checks the battery voltage and temperature thresholds against the ordering rules documented by EPEVER.
+// Rules involving a nil field are skipped. An empty result means no violations were found.
+func (p Parameters) Validate() []Violation {
+	var violations []Violation
+	for _, rule := range parameterRules {
+		value := rule.value(p)
+		otherValue := rule.otherValue(p)
+		if (value == nil) || (otherValue == nil) {
+			continue
+		}
+		var ok bool
+		switch rule.relation {
+		case RelationGreaterThan:
+			ok = *value > *otherValue
+		case RelationGreaterThanOrEqual:
+			ok = *value >= *otherValue
+		}
+		if !ok {
+			violations = append(violations, Violation{
+				Field:      rule.field,
+				Value:      *value,
+				Relation:   rule.relation,
+				Other:      rule.other,
+				OtherValue: *otherValue,
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,60 @@
+package epsolar_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+)
+
+func f64(v float64) *float64 {
+	return &v
+}
+
+func TestWriteParametersValidatesTemperatureLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     epsolar.Parameters
+		valid bool
+	}{
+		{"lower limit above current upper limit", epsolar.Parameters{BatteryTemperatureWarningLowerLimit: f64(70)}, false},
+		{"recover above current upper limit", epsolar.Parameters{ControllerInnerTemperatureUpperLimitRecover: f64(90)}, false},
+		{"recover equal to new upper limit", epsolar.Parameters{ControllerInnerTemperatureUpperLimit: f64(75)}, false},
+		{"valid", epsolar.Parameters{BatteryTemperatureWarningUpperLimit: f64(60), BatteryTemperatureWarningLowerLimit: f64(-20)}, true},
+	}
+	for _, test := range tests {
+		c, err := simulator.NewTracerAN(epsolar.BatteryRatedVoltageLevel12V)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := temperatureLimits(c)
+		dev := epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+		err = dev.WriteParameters(test.p)
+		after := temperatureLimits(c)
+		if test.valid {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		var validationError *epsolar.ValidationError
+		if !errors.As(err, &validationError) {
+			t.Errorf("%s: err = %v, want *ValidationError", test.name, err)
+		}
+		if !slices.Equal(after, before) {
+			t.Errorf("%s: temperature limits written despite validation failure", test.name)
+		}
+	}
+}
+
+func temperatureLimits(c *simulator.Controller) []uint16 {
+	var v []uint16
+	for addr := uint16(0x9017); addr <= 0x901a; addr++ {
+		value, _ := c.HoldingRegister(addr)
+		v = append(v, value)
+	}
+	return v
+}
@@ -0,0 +1,367 @@
+package epsolar
+
+import (
+	"context"
+	"errors"
+
+	"github.com/simonvetter/modbus"
+)
+
+// RegisterGroup is a set of related registers, coils or discrete inputs that a controller either implements
+// in full or not at all.
+type RegisterGroup uint32
+
+const (
+	// RegisterGroupRatedData covers RatedData, except BatteryRealRatedVoltage.
+	RegisterGroupRatedData RegisterGroup = 1 << iota
+	// RegisterGroupBatteryRealRatedVoltage covers RatedData.BatteryRealRatedVoltage.
+	RegisterGroupBatteryRealRatedVoltage
+	// RegisterGroupRealTimeData covers RealTimeData, except the fields of the groups below.
+	RegisterGroupRealTimeData
+	// RegisterGroupBatteryChargingOutput covers RealTimeData.BatteryChargingVoltage, BatteryChargingCurrent and
+	// BatteryChargingPower.
+	RegisterGroupBatteryChargingOutput
+	// RegisterGroupPowerComponentsTemperature covers RealTimeData.PowerComponentsTemperature.
+	RegisterGroupPowerComponentsTemperature
+	// RegisterGroupRemoteBatteryTemperature covers RealTimeData.RemoteBatteryTemperature.
+	RegisterGroupRemoteBatteryTemperature
+	// RegisterGroupRealTimeStatus covers RealTimeStatus, except ChargingDeviceOn.
+	RegisterGroupRealTimeStatus
+	// RegisterGroupStatistics covers Statistics, except CarbonDioxideReduction.
+	RegisterGroupStatistics
+	// RegisterGroupCarbonDioxideReduction covers Statistics.CarbonDioxideReduction.
+	RegisterGroupCarbonDioxideReduction
+	// RegisterGroupBatteryVoltageSettings covers the battery voltage settings block of Parameters (0x9000-0x900e).
+	RegisterGroupBatteryVoltageSettings
+	// RegisterGroupRealTimeClock covers ReadRealTimeClock and SetRealTimeClock.
+	RegisterGroupRealTimeClock
+	// RegisterGroupTemperatureSettings covers Parameters.EqualizeChargingCycle and the temperature limits.
+	RegisterGroupTemperatureSettings
+	// RegisterGroupLightSettings covers the day/night threshold voltages and delays of Parameters.
+	RegisterGroupLightSettings
+	// RegisterGroupLoadControl covers LoadControl, LoadControlSettings and Parameters.DefaultLoadOnOffInManualMode.
+	RegisterGroupLoadControl
+	// RegisterGroupBatteryManagementSettings covers Parameters.BatteryRatedVoltageLevel, EqualizeDuration,
+	// BoostDuration, BatteryDischarge, BatteryCharge and ChargingMode.
+	RegisterGroupBatteryManagementSettings
+	// RegisterGroupLiBatteryProtection covers Parameters.LiBatteryProtectionAndOverTemperatureDropPower.
+	RegisterGroupLiBatteryProtection
+	// RegisterGroupChargingDevice covers RealTimeStatus.ChargingDeviceOn, ReadChargingDevice and
+	// SetChargingDevice.
+	RegisterGroupChargingDevice
+	// RegisterGroupMaintenance covers RestoreDefaults and ClearStatistics.
+	RegisterGroupMaintenance
+)
+
+// RegisterGroupAll is the union of all register groups.
+const RegisterGroupAll = RegisterGroupMaintenance<<1 - 1
+
+func (g RegisterGroup) String() string {
+	switch g {
+	case RegisterGroupRatedData:
+		return "RatedData"
+	case RegisterGroupBatteryRealRatedVoltage:
+		return "BatteryRealRatedVoltage"
+	case RegisterGroupRealTimeData:
+		return "RealTimeData"
+	case RegisterGroupBatteryChargingOutput:
+		return "BatteryChargingOutput"
+	case RegisterGroupPowerComponentsTemperature:
+		return "PowerComponentsTemperature"
+	case RegisterGroupRemoteBatteryTemperature:
+		return "RemoteBatteryTemperature"
+	case RegisterGroupRealTimeStatus:
+		return "RealTimeStatus"
+	case RegisterGroupStatistics:
+		return "Statistics"
+	case RegisterGroupCarbonDioxideReduction:
+		return "CarbonDioxideReduction"
+	case RegisterGroupBatteryVoltageSettings:
+		return "BatteryVoltageSettings"
+	case RegisterGroupRealTimeClock:
+		return "RealTimeClock"
+	case RegisterGroupTemperatureSettings:
+		return "TemperatureSettings"
+	case RegisterGroupLightSettings:
+		return "LightSettings"
+	case RegisterGroupLoadControl:
+		return "LoadControl"
+	case RegisterGroupBatteryManagementSettings:
+		return "BatteryManagementSettings"
+	case RegisterGroupLiBatteryProtection:
+		return "LiBatteryProtection"
+	case RegisterGroupChargingDevice:
+		return "ChargingDevice"
+	case RegisterGroupMaintenance:
+		return "Maintenance"
+	default:
+		return "Unknown"
+	}
+}
+
+// Groups returns the individual groups in g.
+func (g RegisterGroup) Groups() []RegisterGroup {
+	var groups []RegisterGroup
+	for group := RegisterGroup(1); group <= RegisterGroupMaintenance; group <<= 1 {
+		if g&group != 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+type addressSpace uint8
+
+const (
+	coilSpace addressSpace = iota
+	discreteInputSpace
+	holdingRegisterSpace
+	inputRegisterSpace
+)
+
+func registerSpace(regType RegisterType) addressSpace {
+	if regType == HoldingRegister {
+		return holdingRegisterSpace
+	}
+	return inputRegisterSpace
+}
+
+type addressRange struct {
+	space    addressSpace
+	addr     uint16
+	quantity uint16
+}
+
+func (r addressRange) contains(space addressSpace, addr uint16) bool {
+	return (r.space == space) && (addr >= r.addr) && (int(addr) < int(r.addr)+int(r.quantity))
+}
+
+// registerGroupRanges lists the addresses of each group. The first range of each group is used for probing.
+var registerGroupRanges = map[RegisterGroup][]addressRange{
+	RegisterGroupRatedData: {
+		{inputRegisterSpace, 0x3000, 0x11},
+	},
+	RegisterGroupBatteryRealRatedVoltage: {
+		{inputRegisterSpace, 0x311d, 1},
+	},
+	RegisterGroupRealTimeData: {
+		{inputRegisterSpace, 0x3100, 4},
+		{inputRegisterSpace, 0x310c, 6},
+		{inputRegisterSpace, 0x311a, 1},
+		{inputRegisterSpace, 0x331a, 3},
+	},
+	RegisterGroupBatteryChargingOutput: {
+		{inputRegisterSpace, 0x3104, 4},
+	},
+	RegisterGroupPowerComponentsTemperature: {
+		{inputRegisterSpace, 0x3112, 1},
+	},
+	RegisterGroupRemoteBatteryTemperature: {
+		{inputRegisterSpace, 0x311b, 1},
+	},
+	RegisterGroupRealTimeStatus: {
+		{inputRegisterSpace, 0x3200, 3},
+		{discreteInputSpace, 0x2000, 1},
+		{discreteInputSpace, 0x200c, 1},
+	},
+	RegisterGroupStatistics: {
+		{inputRegisterSpace, 0x3300, 0x14},
+	},
+	RegisterGroupCarbonDioxideReduction: {
+		{inputRegisterSpace, 0x3314, 2},
+	},
+	RegisterGroupBatteryVoltageSettings: {
+		{holdingRegisterSpace, 0x9000, 0x0f},
+	},
+	RegisterGroupRealTimeClock: {
+		{holdingRegisterSpace, 0x9013, 3},
+	},
+	RegisterGroupTemperatureSettings: {
+		{holdingRegisterSpace, 0x9016, 5},
+	},
+	RegisterGroupLightSettings: {
+		{holdingRegisterSpace, 0x901e, 4},
+	},
+	RegisterGroupLoadControl: {
+		{holdingRegisterSpace, 0x903d, 0x11},
+		{holdingRegisterSpace, 0x9065, 1},
+		{holdingRegisterSpace, 0x906a, 1},
+		{coilSpace, 0x0002, 2},
+		{coilSpace, 0x0005, 2},
+	},
+	RegisterGroupBatteryManagementSettings: {
+		{holdingRegisterSpace, 0x9067, 1},
+		{holdingRegisterSpace, 0x906b, 4},
+		{holdingRegisterSpace, 0x9070, 1},
+	},
+	RegisterGroupLiBatteryProtection: {
+		{holdingRegisterSpace, 0x9107, 1},
+	},
+	RegisterGroupChargingDevice: {
+		{coilSpace, 0x0000, 1},
+	},
+	RegisterGroupMaintenance: {
+		{coilSpace, 0x0013, 2},
+	},
+}
+
+// ---
+
+// Profile declares the register groups implemented by a controller.
+//
+// Dev skips addresses outside the profile's groups without a Modbus transaction, reporting them as
+// unsupported (nil fields, or modbus.ErrIllegalDataAddress for writes and commands). Addresses that do not
+// belong to any group are always accessed.
+//
+// No published register map lists the groups each model implements, and firmware revisions vary, so only
+// ProfileGeneric is predefined. ProbeProfile builds a profile from the controller itself.
+type Profile struct {
+	Name   string
+	Groups RegisterGroup
+}
+
+var ProfileGeneric = &Profile{
+	Name:   "Generic",
+	Groups: RegisterGroupAll,
+}
+
+// Profiles lists the predefined profiles.
+var Profiles = []*Profile{
+	ProfileGeneric,
+}
+
+// Supports reports whether the profile includes group. A nil profile supports every group.
+func (p *Profile) Supports(group RegisterGroup) bool {
+	if p == nil {
+		return true
+	}
+	return p.Groups&group == group
+}
+
+func (p *Profile) supportsAddress(space addressSpace, addr uint16) bool {
+	if p == nil {
+		return true
+	}
+	for group, ranges := range registerGroupRanges {
+		for _, r := range ranges {
+			if r.contains(space, addr) {
+				return p.Supports(group)
+			}
+		}
+	}
+	return true
+}
+
+// supportsRange reports whether every address in the range is supported.
+func (p *Profile) supportsRange(space addressSpace, addr uint16, quantity uint16) bool {
+	for i := 0; i < int(quantity); i++ {
+		if !p.supportsAddress(space, addr+uint16(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// supportsAnyInRange reports whether at least one address in the range is supported.
+func (p *Profile) supportsAnyInRange(space addressSpace, addr uint16, quantity uint16) bool {
+	for i := 0; i < int(quantity); i++ {
+		if p.supportsAddress(space, addr+uint16(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ---
+
+// Profile returns the profile in use, or nil if every address is accessed.
+func (dev *Dev) Profile() *Profile {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+
+	return dev.profile
+}
+
+// SetProfile sets the profile used to skip unsupported addresses. A nil profile accesses every address.
+func (dev *Dev) SetProfile(profile *Profile) {
+	dev.mutex.Lock()
+	defer dev.mutex.Unlock()
+
+	dev.profile = profile
+}
+
+// SelectProfile builds a profile with ProbeProfile and sets it with SetProfile.
+func (dev *Dev) SelectProfile() (*Profile, error) {
+	return dev.SelectProfileContext(context.Background())
+}
+
+func (dev *Dev) SelectProfileContext(ctx context.Context) (*Profile, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dev.mutex.Unlock()
+
+	profile, err := dev.probeProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dev.profile = profile
+	return profile, nil
+}
+
+// ProbeProfile builds a profile by reading the first address of each register group. Groups answering
+// modbus.ErrIllegalDataAddress or modbus.ErrIllegalFunction are left out. The profile in use is ignored while
+// probing, and is not changed.
+func (dev *Dev) ProbeProfile() (*Profile, error) {
+	return dev.ProbeProfileContext(context.Background())
+}
+
+func (dev *Dev) ProbeProfileContext(ctx context.Context) (*Profile, error) {
+	err := dev.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dev.mutex.Unlock()
+
+	return dev.probeProfile(ctx)
+}
+
+// probeProfile implements ProbeProfileContext. The caller must hold the lock.
+func (dev *Dev) probeProfile(ctx context.Context) (*Profile, error) {
+	err := dev.requestSetup()
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{
+		Name: "Probed",
+	}
+	for _, group := range RegisterGroupAll.Groups() {
+		r := registerGroupRanges[group][0]
+		err = dev.transact(ctx, func() error {
+			switch r.space {
+			case coilSpace:
+				_, err := dev.mc.ReadCoils(r.addr, 1)
+				return err
+			case discreteInputSpace:
+				_, err := dev.mc.ReadDiscreteInputs(r.addr, 1)
+				return err
+			case holdingRegisterSpace:
+				_, err := dev.mc.ReadRegisters(r.addr, 1, HoldingRegister)
+				return err
+			default:
+				_, err := dev.mc.ReadRegisters(r.addr, 1, InputRegister)
+				return err
+			}
+		})
+		if err != nil {
+			if errors.Is(err, modbus.ErrIllegalDataAddress) || errors.Is(err, modbus.ErrIllegalFunction) {
+				continue
+			}
+			return nil, err
+		}
+		profile.Groups |= group
+	}
+	return profile, nil
+}
@@ -0,0 +1,96 @@
+package epsolar_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+)
+
+func newTracerANDev(t *testing.T) *epsolar.Dev {
+	c, err := simulator.NewTracerAN(epsolar.BatteryRatedVoltageLevel12V)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+}
+
+func TestProbeProfile(t *testing.T) {
+	dev := newTracerANDev(t)
+	custom := &epsolar.Profile{
+		Name:   "Custom",
+		Groups: epsolar.RegisterGroupRatedData,
+	}
+	dev.SetProfile(custom)
+	profile, err := dev.ProbeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Groups != epsolar.RegisterGroupAll {
+		t.Errorf("probed groups = %v, want all", profile.Groups.Groups())
+	}
+	if dev.Profile() != custom {
+		t.Errorf("profile in use changed to %v", dev.Profile())
+	}
+}
+
+func TestProbeProfilePartial(t *testing.T) {
+	c := simulator.New()
+	c.SetInputRegisters(0x3000, make([]uint16, 0x11)...)
+	c.SetInputRegisters(0x3100, make([]uint16, 28)...)
+	c.SetInputRegisters(0x331a, make([]uint16, 3)...)
+	dev := epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+	profile, err := dev.ProbeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := epsolar.RegisterGroupRatedData |
+		epsolar.RegisterGroupRealTimeData |
+		epsolar.RegisterGroupBatteryChargingOutput |
+		epsolar.RegisterGroupPowerComponentsTemperature |
+		epsolar.RegisterGroupRemoteBatteryTemperature
+	if profile.Groups != want {
+		t.Errorf("probed groups = %v, want %v", profile.Groups.Groups(), want.Groups())
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	dev := newTracerANDev(t)
+	profile, err := dev.SelectProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (profile.Groups != epsolar.RegisterGroupAll) || (dev.Profile() != profile) {
+		t.Errorf("selected profile = %+v, want probed all", profile)
+	}
+
+	c := simulator.New()
+	c.SetInputRegisters(0x3000, make([]uint16, 0x11)...)
+	dev = epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+	profile, err = dev.SelectProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (profile.Name != "Probed") || (profile.Groups != epsolar.RegisterGroupRatedData) {
+		t.Errorf("selected profile = %+v, want probed RatedData", profile)
+	}
+}
+
+func TestProfileSkipsUnsupportedRegisters(t *testing.T) {
+	dev := newTracerANDev(t)
+	dev.SetProfile(&epsolar.Profile{
+		Name:   "Custom",
+		Groups: epsolar.RegisterGroupAll &^ epsolar.RegisterGroupPowerComponentsTemperature,
+	})
+	r, err := dev.ReadRealTimeData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PowerComponentsTemperature != nil {
+		t.Errorf("PowerComponentsTemperature = %v, want nil", *r.PowerComponentsTemperature)
+	}
+	if r.BatteryTemperature == nil {
+		t.Errorf("BatteryTemperature not reported")
+	}
+}
@@ -1,24 +1,34 @@
 package epsolar
 
 import (
+	"context"
 	"log/slog"
+	"slices"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type PrometheusCollectorHelper struct {
+	// device info
+	deviceInfo *prometheus.Desc
+
 	// real-time data
-	pvArrayInputVoltage *prometheus.Desc
-	pvArrayInputCurrent *prometheus.Desc
-	pvArrayInputPower   *prometheus.Desc
-	loadVoltage         *prometheus.Desc
-	loadCurrent         *prometheus.Desc
-	loadPower           *prometheus.Desc
-	batteryTemperature  *prometheus.Desc
-	deviceTemperature   *prometheus.Desc
-	batterySOC          *prometheus.Desc
-	batteryVoltage      *prometheus.Desc
-	batteryCurrent      *prometheus.Desc
+	pvArrayInputVoltage        *prometheus.Desc
+	pvArrayInputCurrent        *prometheus.Desc
+	pvArrayInputPower          *prometheus.Desc
+	batteryChargingVoltage     *prometheus.Desc
+	batteryChargingCurrent     *prometheus.Desc
+	batteryChargingPower       *prometheus.Desc
+	loadVoltage                *prometheus.Desc
+	loadCurrent                *prometheus.Desc
+	loadPower                  *prometheus.Desc
+	batteryTemperature         *prometheus.Desc
+	deviceTemperature          *prometheus.Desc
+	powerComponentsTemperature *prometheus.Desc
+	batterySOC                 *prometheus.Desc
+	remoteBatteryTemperature   *prometheus.Desc
+	batteryVoltage             *prometheus.Desc
+	batteryCurrent             *prometheus.Desc
 
 	// real-time status
 	batteryStatus              *prometheus.Desc
@@ -38,10 +48,17 @@ type PrometheusCollectorHelper struct {
 	generatedEnergyThisMonth *prometheus.Desc
 	generatedEnergyThisYear  *prometheus.Desc
 	totalGeneratedEnergy     *prometheus.Desc
+	carbonDioxideReduction   *prometheus.Desc
 }
 
 func NewPrometheusCollectorHelper(variableLabels []string, constLabels prometheus.Labels) *PrometheusCollectorHelper {
 	return &PrometheusCollectorHelper{
+		deviceInfo: prometheus.NewDesc(
+			"epever_solar_device_info",
+			"Device info",
+			append(slices.Clone(variableLabels), "vendor", "product_code", "model", "firmware_version"),
+			constLabels),
+
 		pvArrayInputVoltage: prometheus.NewDesc(
 			"epever_solar_pv_array_input_voltage",
 			"PV array input voltage (V)",
@@ -54,6 +71,18 @@ func NewPrometheusCollectorHelper(variableLabels []string, constLabels prometheu
 			"epever_solar_pv_array_input_power",
 			"PV array input power (W)",
 			variableLabels, constLabels),
+		batteryChargingVoltage: prometheus.NewDesc(
+			"epever_solar_battery_charging_voltage",
+			"Battery charging voltage (V)",
+			variableLabels, constLabels),
+		batteryChargingCurrent: prometheus.NewDesc(
+			"epever_solar_battery_charging_current",
+			"Battery charging current (A)",
+			variableLabels, constLabels),
+		batteryChargingPower: prometheus.NewDesc(
+			"epever_solar_battery_charging_power",
+			"Battery charging power (W)",
+			variableLabels, constLabels),
 		loadVoltage: prometheus.NewDesc(
 			"epever_solar_load_voltage",
 			"Load voltage (V)",
@@ -74,10 +103,18 @@ func NewPrometheusCollectorHelper(variableLabels []string, constLabels prometheu
 			"epever_solar_device_temperature",
 			"Device temperature (°C)",
 			variableLabels, constLabels),
+		powerComponentsTemperature: prometheus.NewDesc(
+			"epever_solar_power_components_temperature",
+			"Power components temperature (°C)",
+			variableLabels, constLabels),
 		batterySOC: prometheus.NewDesc(
 			"epever_solar_battery_remaining_capacity",
 			"Battery remaining capacity (%)",
 			variableLabels, constLabels),
+		remoteBatteryTemperature: prometheus.NewDesc(
+			"epever_solar_remote_battery_temperature",
+			"Remote battery temperature (°C)",
+			variableLabels, constLabels),
 		batteryVoltage: prometheus.NewDesc(
 			"epever_solar_battery_voltage",
 			"Battery voltage (V)",
@@ -148,19 +185,30 @@ func NewPrometheusCollectorHelper(variableLabels []string, constLabels prometheu
 			"epever_solar_total_generated_energy",
 			"Total generated energy (kWh)",
 			variableLabels, constLabels),
+		carbonDioxideReduction: prometheus.NewDesc(
+			"epever_solar_carbon_dioxide_reduction",
+			"Carbon dioxide reduction (t)",
+			variableLabels, constLabels),
 	}
 }
 
 func (c *PrometheusCollectorHelper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.deviceInfo
+
 	ch <- c.pvArrayInputVoltage
 	ch <- c.pvArrayInputCurrent
 	ch <- c.pvArrayInputPower
+	ch <- c.batteryChargingVoltage
+	ch <- c.batteryChargingCurrent
+	ch <- c.batteryChargingPower
 	ch <- c.loadVoltage
 	ch <- c.loadCurrent
 	ch <- c.loadPower
 	ch <- c.batteryTemperature
 	ch <- c.deviceTemperature
+	ch <- c.powerComponentsTemperature
 	ch <- c.batterySOC
+	ch <- c.remoteBatteryTemperature
 	ch <- c.batteryVoltage
 	ch <- c.batteryCurrent
 
@@ -180,10 +228,39 @@ func (c *PrometheusCollectorHelper) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.generatedEnergyThisMonth
 	ch <- c.generatedEnergyThisYear
 	ch <- c.totalGeneratedEnergy
+	ch <- c.carbonDioxideReduction
 }
 
 func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metric, labelValues ...string) {
-	realTimeData, err := dev.ReadRealTimeData()
+	c.CollectContext(context.Background(), dev, ch, labelValues...)
+}
+
+func (c *PrometheusCollectorHelper) CollectContext(ctx context.Context, dev *Dev, ch chan<- prometheus.Metric, labelValues ...string) {
+	deviceInfo, err := dev.ReadDeviceInfoContext(ctx)
+	if err != nil {
+		slog.Warn("failed to read device info",
+			slog.Any("error", err),
+		)
+	} else if (deviceInfo.VendorName != nil) || (deviceInfo.ProductCode != nil) || (deviceInfo.ModelName != nil) || (deviceInfo.FirmwareVersion != nil) {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					slog.Error("failed to create metric",
+						slog.Any("error", err),
+					)
+				}
+			}()
+			ch <- prometheus.MustNewConstMetric(c.deviceInfo, prometheus.GaugeValue, 1,
+				append(slices.Clone(labelValues),
+					stringOrEmpty(deviceInfo.VendorName),
+					stringOrEmpty(deviceInfo.ProductCode),
+					stringOrEmpty(deviceInfo.ModelName),
+					stringOrEmpty(deviceInfo.FirmwareVersion),
+				)...)
+		}()
+	}
+
+	realTimeData, err := dev.ReadRealTimeDataContext(ctx)
 	if err != nil {
 		slog.Warn("failed to read real-time data",
 			slog.Any("error", err),
@@ -206,6 +283,15 @@ func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metri
 			if realTimeData.PVArrayInputPower != nil {
 				ch <- prometheus.MustNewConstMetric(c.pvArrayInputPower, prometheus.GaugeValue, *realTimeData.PVArrayInputPower, labelValues...)
 			}
+			if realTimeData.BatteryChargingVoltage != nil {
+				ch <- prometheus.MustNewConstMetric(c.batteryChargingVoltage, prometheus.GaugeValue, *realTimeData.BatteryChargingVoltage, labelValues...)
+			}
+			if realTimeData.BatteryChargingCurrent != nil {
+				ch <- prometheus.MustNewConstMetric(c.batteryChargingCurrent, prometheus.GaugeValue, *realTimeData.BatteryChargingCurrent, labelValues...)
+			}
+			if realTimeData.BatteryChargingPower != nil {
+				ch <- prometheus.MustNewConstMetric(c.batteryChargingPower, prometheus.GaugeValue, *realTimeData.BatteryChargingPower, labelValues...)
+			}
 			if realTimeData.LoadVoltage != nil {
 				ch <- prometheus.MustNewConstMetric(c.loadVoltage, prometheus.GaugeValue, *realTimeData.LoadVoltage, labelValues...)
 			}
@@ -221,9 +307,15 @@ func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metri
 			if realTimeData.DeviceTemperature != nil {
 				ch <- prometheus.MustNewConstMetric(c.deviceTemperature, prometheus.GaugeValue, *realTimeData.DeviceTemperature, labelValues...)
 			}
+			if realTimeData.PowerComponentsTemperature != nil {
+				ch <- prometheus.MustNewConstMetric(c.powerComponentsTemperature, prometheus.GaugeValue, *realTimeData.PowerComponentsTemperature, labelValues...)
+			}
 			if realTimeData.BatterySOC != nil {
 				ch <- prometheus.MustNewConstMetric(c.batterySOC, prometheus.GaugeValue, *realTimeData.BatterySOC, labelValues...)
 			}
+			if realTimeData.RemoteBatteryTemperature != nil {
+				ch <- prometheus.MustNewConstMetric(c.remoteBatteryTemperature, prometheus.GaugeValue, *realTimeData.RemoteBatteryTemperature, labelValues...)
+			}
 			if realTimeData.BatteryVoltage != nil {
 				ch <- prometheus.MustNewConstMetric(c.batteryVoltage, prometheus.GaugeValue, *realTimeData.BatteryVoltage, labelValues...)
 			}
@@ -233,7 +325,7 @@ func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metri
 		}()
 	}
 
-	realTimeStatus, err := dev.ReadRealTimeStatus()
+	realTimeStatus, err := dev.ReadRealTimeStatusContext(ctx)
 	if err != nil {
 		slog.Warn("failed to read real-time status",
 			slog.Any("error", err),
@@ -259,7 +351,7 @@ func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metri
 		}()
 	}
 
-	statistics, err := dev.ReadStatistics()
+	statistics, err := dev.ReadStatisticsContext(ctx)
 	if err != nil {
 		slog.Warn("failed to read statistics",
 			slog.Any("error", err),
@@ -309,6 +401,16 @@ func (c *PrometheusCollectorHelper) Collect(dev *Dev, ch chan<- prometheus.Metri
 			if statistics.TotalGeneratedEnergy != nil {
 				ch <- prometheus.MustNewConstMetric(c.totalGeneratedEnergy, prometheus.GaugeValue, *statistics.TotalGeneratedEnergy, labelValues...)
 			}
+			if statistics.CarbonDioxideReduction != nil {
+				ch <- prometheus.MustNewConstMetric(c.carbonDioxideReduction, prometheus.GaugeValue, *statistics.CarbonDioxideReduction, labelValues...)
+			}
 		}()
 	}
 }
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -1,15 +1,20 @@
 package epsolar
 
 type RealTimeData struct {
-	PVArrayInputVoltage *float64 // V
-	PVArrayInputCurrent *float64 // A
-	PVArrayInputPower   *float64 // W
-	LoadVoltage         *float64 // V
-	LoadCurrent         *float64 // A
-	LoadPower           *float64 // W
-	BatteryTemperature  *float64 // C
-	DeviceTemperature   *float64 // C
-	BatterySOC          *float64 // %
-	BatteryVoltage      *float64 // V
-	BatteryCurrent      *float64 // A
+	PVArrayInputVoltage        *float64 // V
+	PVArrayInputCurrent        *float64 // A
+	PVArrayInputPower          *float64 // W
+	BatteryChargingVoltage     *float64 // V
+	BatteryChargingCurrent     *float64 // A
+	BatteryChargingPower       *float64 // W
+	LoadVoltage                *float64 // V
+	LoadCurrent                *float64 // A
+	LoadPower                  *float64 // W
+	BatteryTemperature         *float64 // C
+	DeviceTemperature          *float64 // C
+	PowerComponentsTemperature *float64 // C
+	BatterySOC                 *float64 // %
+	RemoteBatteryTemperature   *float64 // C
+	BatteryVoltage             *float64 // V
+	BatteryCurrent             *float64 // A
 }
@@ -8,6 +8,7 @@ import (
 type RealTimeStatus struct {
 	OverTemperatureInsideTheDevice *bool
 	Night                          *bool
+	ChargingDeviceOn               *bool
 	BatteryStatus                  *BatteryStatusDetails
 	ChargingEquipmentStatus        *ChargingEquipmentStatusDetails
 	DischargingEquipmentStatus     *DischargingEquipmentStatusDetails
@@ -0,0 +1,312 @@
+package epsolar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+// TransactionRecord is one Modbus transaction, as written by RecordingTransport and read by ReplayTransport.
+type TransactionRecord struct {
+	Time         time.Time                             `json:"time"`
+	Function     string                                `json:"function"`
+	RegisterType *RegisterType                         `json:"registerType,omitempty"`
+	Address      uint16                                `json:"address"`
+	Count        uint16                                `json:"count"`
+	Registers    []uint16                              `json:"registers,omitempty"` // written or read registers
+	Bits         []bool                                `json:"bits,omitempty"`      // written or read coils / discrete inputs
+	Objects      map[DeviceIdentificationObject]string `json:"objects,omitempty"`   // device identification objects
+	Error        string                                `json:"error,omitempty"`
+	Latency      time.Duration                         `json:"latency"` // nanoseconds
+}
+
+const (
+	functionReadRegisters            = "ReadRegisters"
+	functionReadCoils                = "ReadCoils"
+	functionReadDiscreteInputs       = "ReadDiscreteInputs"
+	functionWriteRegister            = "WriteRegister"
+	functionWriteRegisters           = "WriteRegisters"
+	functionWriteCoil                = "WriteCoil"
+	functionReadDeviceIdentification = "ReadDeviceIdentification"
+)
+
+// RecordingTransport wraps a Transport and writes every transaction to w as JSON Lines.
+type RecordingTransport struct {
+	mutex     sync.Mutex
+	transport Transport
+	encoder   *json.Encoder
+}
+
+func NewRecordingTransport(transport Transport, w io.Writer) *RecordingTransport {
+	return &RecordingTransport{
+		transport: transport,
+		encoder:   json.NewEncoder(w),
+	}
+}
+
+func (t *RecordingTransport) record(record TransactionRecord, start time.Time, err error) error {
+	record.Time = start
+	record.Latency = time.Since(start)
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	encodeErr := t.encoder.Encode(record)
+	if (err == nil) && (encodeErr != nil) {
+		return encodeErr
+	}
+	return err
+}
+
+func (t *RecordingTransport) SetUnitId(id uint8) error {
+	return t.transport.SetUnitId(id)
+}
+
+func (t *RecordingTransport) ReadRegisters(addr uint16, quantity uint16, regType RegisterType) ([]uint16, error) {
+	start := time.Now()
+	v, err := t.transport.ReadRegisters(addr, quantity, regType)
+	err = t.record(TransactionRecord{
+		Function:     functionReadRegisters,
+		RegisterType: &regType,
+		Address:      addr,
+		Count:        quantity,
+		Registers:    v,
+	}, start, err)
+	return v, err
+}
+
+func (t *RecordingTransport) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	start := time.Now()
+	v, err := t.transport.ReadCoils(addr, quantity)
+	err = t.record(TransactionRecord{
+		Function: functionReadCoils,
+		Address:  addr,
+		Count:    quantity,
+		Bits:     v,
+	}, start, err)
+	return v, err
+}
+
+func (t *RecordingTransport) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	start := time.Now()
+	v, err := t.transport.ReadDiscreteInputs(addr, quantity)
+	err = t.record(TransactionRecord{
+		Function: functionReadDiscreteInputs,
+		Address:  addr,
+		Count:    quantity,
+		Bits:     v,
+	}, start, err)
+	return v, err
+}
+
+func (t *RecordingTransport) WriteRegister(addr uint16, value uint16) error {
+	start := time.Now()
+	err := t.transport.WriteRegister(addr, value)
+	return t.record(TransactionRecord{
+		Function:  functionWriteRegister,
+		Address:   addr,
+		Count:     1,
+		Registers: []uint16{value},
+	}, start, err)
+}
+
+func (t *RecordingTransport) WriteRegisters(addr uint16, values []uint16) error {
+	start := time.Now()
+	err := t.transport.WriteRegisters(addr, values)
+	return t.record(TransactionRecord{
+		Function:  functionWriteRegisters,
+		Address:   addr,
+		Count:     uint16(len(values)),
+		Registers: values,
+	}, start, err)
+}
+
+func (t *RecordingTransport) WriteCoil(addr uint16, value bool) error {
+	start := time.Now()
+	err := t.transport.WriteCoil(addr, value)
+	return t.record(TransactionRecord{
+		Function: functionWriteCoil,
+		Address:  addr,
+		Count:    1,
+		Bits:     []bool{value},
+	}, start, err)
+}
+
+// ReadDeviceIdentification forwards to the wrapped Transport if it implements DeviceIdentifier, and fails with
+// modbus.ErrIllegalFunction otherwise.
+func (t *RecordingTransport) ReadDeviceIdentification() (map[DeviceIdentificationObject]string, error) {
+	start := time.Now()
+	var v map[DeviceIdentificationObject]string
+	var err error
+	if identifier, ok := t.transport.(DeviceIdentifier); ok {
+		v, err = identifier.ReadDeviceIdentification()
+	} else {
+		err = modbus.ErrIllegalFunction
+	}
+	err = t.record(TransactionRecord{
+		Function: functionReadDeviceIdentification,
+		Objects:  v,
+	}, start, err)
+	return v, err
+}
+
+// ---
+
+// ReplayTransport serves transactions recorded by RecordingTransport.
+//
+// Each request is answered by the next unused record with the same function, register type,
+// address and count; requests without a matching record fail. Recorded Modbus errors are
+// returned as the corresponding modbus.Error values, so errors.Is works as with a live device.
+// Latencies are not reproduced.
+type ReplayTransport struct {
+	mutex   sync.Mutex
+	records []TransactionRecord
+	used    []bool
+}
+
+func NewReplayTransport(r io.Reader) (*ReplayTransport, error) {
+	t := &ReplayTransport{}
+	decoder := json.NewDecoder(r)
+	for {
+		var record TransactionRecord
+		err := decoder.Decode(&record)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		t.records = append(t.records, record)
+	}
+	t.used = make([]bool, len(t.records))
+	return t, nil
+}
+
+var modbusErrors = []error{
+	modbus.ErrConfigurationError,
+	modbus.ErrRequestTimedOut,
+	modbus.ErrIllegalFunction,
+	modbus.ErrIllegalDataAddress,
+	modbus.ErrIllegalDataValue,
+	modbus.ErrServerDeviceFailure,
+	modbus.ErrAcknowledge,
+	modbus.ErrServerDeviceBusy,
+	modbus.ErrMemoryParityError,
+	modbus.ErrGWPathUnavailable,
+	modbus.ErrGWTargetFailedToRespond,
+	modbus.ErrBadCRC,
+	modbus.ErrShortFrame,
+	modbus.ErrProtocolError,
+	modbus.ErrBadUnitId,
+	modbus.ErrBadTransactionId,
+	modbus.ErrUnknownProtocolId,
+	modbus.ErrUnexpectedParameters,
+}
+
+func replayError(s string) error {
+	if s == "" {
+		return nil
+	}
+	for _, err := range modbusErrors {
+		if err.Error() == s {
+			return err
+		}
+	}
+	return errors.New(s)
+}
+
+func (t *ReplayTransport) next(function string, regType *RegisterType, addr uint16, count uint16) (TransactionRecord, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, record := range t.records {
+		if t.used[i] || (record.Function != function) || (record.Address != addr) || (record.Count != count) {
+			continue
+		}
+		if (regType != nil) && ((record.RegisterType == nil) || (*record.RegisterType != *regType)) {
+			continue
+		}
+		t.used[i] = true
+		return record, nil
+	}
+	return TransactionRecord{}, fmt.Errorf("no recorded %s transaction for address 0x%04x, count %d", function, addr, count)
+}
+
+// replayResult returns the values of a recorded read, checking that a successful read returned quantity values.
+func replayResult[T any](record TransactionRecord, values []T, quantity uint16) ([]T, error) {
+	if record.Error != "" {
+		return values, replayError(record.Error)
+	}
+	if len(values) != int(quantity) {
+		return nil, fmt.Errorf("recorded %s transaction for address 0x%04x has %d values, expected %d", record.Function, record.Address, len(values), quantity)
+	}
+	return values, nil
+}
+
+func (t *ReplayTransport) SetUnitId(id uint8) error {
+	return nil
+}
+
+func (t *ReplayTransport) ReadRegisters(addr uint16, quantity uint16, regType RegisterType) ([]uint16, error) {
+	record, err := t.next(functionReadRegisters, &regType, addr, quantity)
+	if err != nil {
+		return nil, err
+	}
+	return replayResult(record, record.Registers, quantity)
+}
+
+func (t *ReplayTransport) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	record, err := t.next(functionReadCoils, nil, addr, quantity)
+	if err != nil {
+		return nil, err
+	}
+	return replayResult(record, record.Bits, quantity)
+}
+
+func (t *ReplayTransport) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	record, err := t.next(functionReadDiscreteInputs, nil, addr, quantity)
+	if err != nil {
+		return nil, err
+	}
+	return replayResult(record, record.Bits, quantity)
+}
+
+func (t *ReplayTransport) WriteRegister(addr uint16, value uint16) error {
+	record, err := t.next(functionWriteRegister, nil, addr, 1)
+	if err != nil {
+		return err
+	}
+	return replayError(record.Error)
+}
+
+func (t *ReplayTransport) WriteRegisters(addr uint16, values []uint16) error {
+	record, err := t.next(functionWriteRegisters, nil, addr, uint16(len(values)))
+	if err != nil {
+		return err
+	}
+	return replayError(record.Error)
+}
+
+func (t *ReplayTransport) WriteCoil(addr uint16, value bool) error {
+	record, err := t.next(functionWriteCoil, nil, addr, 1)
+	if err != nil {
+		return err
+	}
+	return replayError(record.Error)
+}
+
+func (t *ReplayTransport) ReadDeviceIdentification() (map[DeviceIdentificationObject]string, error) {
+	record, err := t.next(functionReadDeviceIdentification, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return record.Objects, replayError(record.Error)
+}
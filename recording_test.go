@@ -0,0 +1,88 @@
+package epsolar_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+	"github.com/simonvetter/modbus"
+)
+
+func TestRecordReplay(t *testing.T) {
+	c, err := simulator.NewTracerAN(epsolar.BatteryRatedVoltageLevel12V)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := simulator.NewFaultInjector(c)
+	_, err = f.Inject(simulator.Fault{
+		Kind:     simulator.FaultException,
+		Table:    simulator.TableHoldingRegisters,
+		Addr:     0x9107,
+		Quantity: 1,
+		Err:      modbus.ErrIllegalDataAddress,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	dev := epsolar.NewWithTransport(epsolar.NewRecordingTransport(f, &buf), 1, &sync.Mutex{})
+	status, err := dev.ReadRealTimeStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters, err := dev.ReadParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parameters.LiBatteryProtectionAndOverTemperatureDropPower != nil {
+		t.Fatalf("LiBatteryProtectionAndOverTemperatureDropPower read despite fault")
+	}
+	recording := buf.Bytes()
+
+	replay, err := epsolar.NewReplayTransport(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev = epsolar.NewWithTransport(replay, 1, &sync.Mutex{})
+	replayedStatus, err := dev.ReadRealTimeStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayedStatus, status) {
+		t.Errorf("replayed real-time status = %+v, want %+v", replayedStatus, status)
+	}
+	replayedParameters, err := dev.ReadParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayedParameters, parameters) {
+		t.Errorf("replayed parameters = %+v, want %+v", replayedParameters, parameters)
+	}
+
+	// recorded Modbus errors replay as the same sentinel
+	replay, err = epsolar.NewReplayTransport(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = replay.ReadRegisters(0x9107, 1, epsolar.HoldingRegister)
+	if !errors.Is(err, modbus.ErrIllegalDataAddress) {
+		t.Errorf("replayed 0x9107 error = %v, want %v", err, modbus.ErrIllegalDataAddress)
+	}
+}
+
+func TestReplayTruncatedRecord(t *testing.T) {
+	recording := `{"function":"ReadRegisters","registerType":1,"address":12544,"count":28,"registers":[1,2,3]}`
+	replay, err := epsolar.NewReplayTransport(bytes.NewReader([]byte(recording)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = replay.ReadRegisters(0x3100, 28, epsolar.InputRegister)
+	if err == nil {
+		t.Errorf("truncated record replayed without error")
+	}
+}
@@ -0,0 +1,95 @@
+package epsolar
+
+import (
+	"context"
+	"errors"
+
+	"github.com/simonvetter/modbus"
+)
+
+// registerBlock holds a contiguous range of registers fetched in a single request.
+// If the range could not be fetched because it spans an illegal data address,
+// values is nil and each accessor falls back to reading its register(s) individually.
+// Registers outside the Dev's profile read as unsupported even if the block was fetched;
+// the block is not fetched at all if none of its registers are in the profile.
+type registerBlock struct {
+	dev     *Dev
+	regType RegisterType
+	addr    uint16
+	values  []uint16
+}
+
+func (dev *Dev) readRegisterBlock(ctx context.Context, addr uint16, quantity uint16, regType RegisterType) (*registerBlock, error) {
+	b := &registerBlock{
+		dev:     dev,
+		regType: regType,
+		addr:    addr,
+	}
+	if !dev.profile.supportsAnyInRange(registerSpace(regType), addr, quantity) {
+		return b, nil
+	}
+	v, err := dev.mcReadRegisterRange(ctx, addr, quantity, regType)
+	if err != nil {
+		if !errors.Is(err, modbus.ErrIllegalDataAddress) {
+			return nil, err
+		}
+	} else {
+		b.values = v
+	}
+	return b, nil
+}
+
+func (b *registerBlock) fetch(ctx context.Context, addr uint16, quantity uint16) ([]uint16, error) {
+	if !b.dev.profile.supportsRange(registerSpace(b.regType), addr, quantity) {
+		return nil, nil
+	}
+	if b.values != nil {
+		offset := int(addr) - int(b.addr)
+		return b.values[offset : offset+int(quantity)], nil
+	}
+	v, err := b.dev.mcReadRegisters(ctx, addr, quantity, b.regType)
+	if err != nil {
+		if errors.Is(err, modbus.ErrIllegalDataAddress) {
+			return nil, nil
+		} else {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (b *registerBlock) uint16ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := b.fetch(ctx, addr, 1)
+	if (err != nil) || (v == nil) {
+		return nil, err
+	}
+	f64 := float64(v[0]) / divisor
+	return &f64, nil
+}
+
+func (b *registerBlock) int16ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := b.fetch(ctx, addr, 1)
+	if (err != nil) || (v == nil) {
+		return nil, err
+	}
+	f64 := float64(int16(v[0])) / divisor
+	return &f64, nil
+}
+
+func (b *registerBlock) uint32ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := b.fetch(ctx, addr, 2)
+	if (err != nil) || (v == nil) {
+		return nil, err
+	}
+	f64 := float64(decodeUint32(v)) / divisor
+	return &f64, nil
+}
+
+func (b *registerBlock) int32ToFloat64(ctx context.Context, addr uint16, divisor float64) (*float64, error) {
+	v, err := b.fetch(ctx, addr, 2)
+	if (err != nil) || (v == nil) {
+		return nil, err
+	}
+	f64 := float64(decodeInt32(v)) / divisor
+	return &f64, nil
+}
@@ -0,0 +1,65 @@
+package epsolar_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+)
+
+// newRealTimeDataController returns a Controller with the real-time data registers set. If gap is true,
+// 0x3113 is left unset so that the 0x3100 block cannot be read in a single request.
+func newRealTimeDataController(temperature uint16, netCurrent uint32, gap bool) *simulator.Controller {
+	c := simulator.New()
+	if gap {
+		c.SetInputRegisters(0x3100, make([]uint16, 0x13)...)
+		c.SetInputRegisters(0x3114, make([]uint16, 8)...)
+	} else {
+		c.SetInputRegisters(0x3100, make([]uint16, 28)...)
+	}
+	c.SetInputRegisters(0x3110, temperature, temperature)
+	c.SetInputRegisters(0x331a, make([]uint16, 3)...)
+	c.SetInputRegisterUint32(0x331b, netCurrent)
+	return c
+}
+
+func TestReadRealTimeDataSigned(t *testing.T) {
+	tests := []struct {
+		name        string
+		temperature uint16
+		netCurrent  uint32
+		wantTemp    float64
+		wantCurrent float64
+	}{
+		{"max positive", 0x7fff, 0x7fffffff, 327.67, 21474836.47},
+		{"min negative", 0x8000, 0x80000000, -327.68, -21474836.48},
+		{"small negative", 0xff9c, 0xfffffc18, -1.00, -10.00},
+	}
+	for _, test := range tests {
+		for _, gap := range []bool{false, true} {
+			c := newRealTimeDataController(test.temperature, test.netCurrent, gap)
+			dev := epsolar.NewWithTransport(c, 1, &sync.Mutex{})
+			r, err := dev.ReadRealTimeData()
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if (r.BatteryTemperature == nil) || (*r.BatteryTemperature != test.wantTemp) {
+				t.Errorf("%s (gap %v): BatteryTemperature = %v, want %v", test.name, gap, deref(r.BatteryTemperature), test.wantTemp)
+			}
+			if (r.DeviceTemperature == nil) || (*r.DeviceTemperature != test.wantTemp) {
+				t.Errorf("%s (gap %v): DeviceTemperature = %v, want %v", test.name, gap, deref(r.DeviceTemperature), test.wantTemp)
+			}
+			if (r.BatteryCurrent == nil) || (*r.BatteryCurrent != test.wantCurrent) {
+				t.Errorf("%s (gap %v): BatteryCurrent = %v, want %v", test.name, gap, deref(r.BatteryCurrent), test.wantCurrent)
+			}
+		}
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,90 @@
+package epsolar
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+// RetryPolicy controls how Dev retries Modbus transactions that fail with a transient error.
+//
+// The zero value performs a single attempt with no retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per transaction, including the first. Values below 1 are
+	// treated as 1.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier scales the delay after each retry. Values below 1 are treated as 1.
+	Multiplier float64
+	// Retryable reports whether err is worth retrying. If nil, IsRetryable is used.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy suitable for a noisy RS485 bus: 3 attempts, backing off from 100ms
+// to at most 1s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     1 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// IsRetryable reports whether err is a transient Modbus error: a timeout, a corrupted or truncated frame,
+// or a busy controller.
+func IsRetryable(err error) bool {
+	return errors.Is(err, modbus.ErrRequestTimedOut) ||
+		errors.Is(err, modbus.ErrBadCRC) ||
+		errors.Is(err, modbus.ErrShortFrame) ||
+		errors.Is(err, modbus.ErrProtocolError) ||
+		errors.Is(err, modbus.ErrBadTransactionId) ||
+		errors.Is(err, modbus.ErrServerDeviceBusy) ||
+		errors.Is(err, modbus.ErrAcknowledge) ||
+		errors.Is(err, modbus.ErrGWTargetFailedToRespond)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	multiplier := max(p.Multiplier, 1)
+	for i := 0; i < retry; i++ {
+		d *= multiplier
+		if (p.MaxBackoff > 0) && (d >= float64(p.MaxBackoff)) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(d)
+}
+
+// do runs fn until it succeeds, fails with a non-retryable error, runs out of attempts, or ctx is done.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if (err == nil) || (attempt+1 >= attempts) || !p.retryable(err) {
+			return err
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package simulator
+
+import (
+	"math"
+
+	"github.com/ngyewch/epever-solar"
+)
+
+// openCircuitVoltage returns the resting voltage of a battery of the given type at soc (0-1).
+// scale is the number of 12V lead-acid blocks and is only used for lead-acid types.
+func openCircuitVoltage(batteryType epsolar.BatteryType, soc float64, scale float64) float64 {
+	switch batteryType {
+	case epsolar.BatteryTypeLiFePO4_4s:
+		return 4 * lifepo4CellVoltage(soc)
+	case epsolar.BatteryTypeLiFePO4_8s:
+		return 8 * lifepo4CellVoltage(soc)
+	case epsolar.BatteryTypeLiFePO4_15s:
+		return 15 * lifepo4CellVoltage(soc)
+	case epsolar.BatteryTypeLiFePO4_16s:
+		return 16 * lifepo4CellVoltage(soc)
+	case epsolar.BatteryTypeLiNiCoMnO2_3s:
+		return 3 * ncmCellVoltage(soc)
+	case epsolar.BatteryTypeLiNiCoMnO2_6s:
+		return 6 * ncmCellVoltage(soc)
+	case epsolar.BatteryTypeLiNiCoMnO2_7s:
+		return 7 * ncmCellVoltage(soc)
+	case epsolar.BatteryTypeLiNiCoMnO2_13s:
+		return 13 * ncmCellVoltage(soc)
+	case epsolar.BatteryTypeLiNiCoMnO2_14s:
+		return 14 * ncmCellVoltage(soc)
+	default:
+		return 6 * scale * leadAcidCellVoltage(soc)
+	}
+}
+
+func leadAcidCellVoltage(soc float64) float64 {
+	return 1.95 + 0.2*soc
+}
+
+// lifepo4CellVoltage models the steep ends and flat middle of the LiFePO4 discharge curve.
+func lifepo4CellVoltage(soc float64) float64 {
+	switch {
+	case soc < 0.1:
+		return 2.8 + 0.4*soc/0.1
+	case soc < 0.9:
+		return 3.2 + 0.1*(soc-0.1)/0.8
+	default:
+		return 3.3 + 0.1*(soc-0.9)/0.1
+	}
+}
+
+func ncmCellVoltage(soc float64) float64 {
+	return 3.3 + 0.85*soc
+}
+
+// internalResistance returns the effective resistance of the battery at soc (0-1). While charging it
+// rises steeply as the battery approaches full charge so that the current tapers off at constant voltage.
+func internalResistance(soc float64, scale float64, charging bool) float64 {
+	if !charging {
+		return 0.02 * scale
+	}
+	return 0.02 * scale * (1 + 200*math.Pow(soc, 12))
+}
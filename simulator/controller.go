@@ -0,0 +1,250 @@
+package simulator
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/simonvetter/modbus"
+)
+
+// Controller is an in-memory EPEVER controller register image.
+//
+// It implements epsolar.Transport, so it can be used directly by epsolar.Dev, and modbus.RequestHandler,
+// so it can be served by a Modbus TCP server (see NewServer). Addresses that have not been set
+// are reported as modbus.ErrIllegalDataAddress.
+//
+// It also implements epsolar.DeviceIdentifier, reporting modbus.ErrIllegalFunction until
+// SetDeviceIdentification is called. The Modbus TCP server does not serve device identification.
+type Controller struct {
+	mutex                sync.Mutex
+	inputRegisters       map[uint16]uint16
+	holdingRegisters     map[uint16]uint16
+	discreteInputs       map[uint16]bool
+	coils                map[uint16]bool
+	deviceIdentification map[epsolar.DeviceIdentificationObject]string
+}
+
+func New() *Controller {
+	return &Controller{
+		inputRegisters:   make(map[uint16]uint16),
+		holdingRegisters: make(map[uint16]uint16),
+		discreteInputs:   make(map[uint16]bool),
+		coils:            make(map[uint16]bool),
+	}
+}
+
+// NewServer returns a Modbus server for c listening at url (e.g. tcp://localhost:5020).
+// The server is not started.
+func (c *Controller) NewServer(url string) (*modbus.ModbusServer, error) {
+	return modbus.NewServer(&modbus.ServerConfiguration{
+		URL: url,
+	}, c)
+}
+
+// ---
+
+func (c *Controller) InputRegister(addr uint16) (uint16, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.inputRegisters[addr]
+	return v, ok
+}
+
+func (c *Controller) SetInputRegisters(addr uint16, values ...uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, v := range values {
+		c.inputRegisters[addr+uint16(i)] = v
+	}
+}
+
+// SetInputRegisterUint32 sets a low word first 32-bit value.
+func (c *Controller) SetInputRegisterUint32(addr uint16, v uint32) {
+	c.SetInputRegisters(addr, uint16(v), uint16(v>>16))
+}
+
+func (c *Controller) HoldingRegister(addr uint16) (uint16, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.holdingRegisters[addr]
+	return v, ok
+}
+
+func (c *Controller) SetHoldingRegisters(addr uint16, values ...uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, v := range values {
+		c.holdingRegisters[addr+uint16(i)] = v
+	}
+}
+
+func (c *Controller) DiscreteInput(addr uint16) (bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.discreteInputs[addr]
+	return v, ok
+}
+
+func (c *Controller) SetDiscreteInputs(addr uint16, values ...bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, v := range values {
+		c.discreteInputs[addr+uint16(i)] = v
+	}
+}
+
+func (c *Controller) Coil(addr uint16) (bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.coils[addr]
+	return v, ok
+}
+
+func (c *Controller) SetCoils(addr uint16, values ...bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, v := range values {
+		c.coils[addr+uint16(i)] = v
+	}
+}
+
+func (c *Controller) SetDeviceIdentification(objects map[epsolar.DeviceIdentificationObject]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.deviceIdentification = maps.Clone(objects)
+}
+
+// ---
+
+func readRange[T any](m map[uint16]T, addr uint16, quantity uint16) ([]T, error) {
+	values := make([]T, quantity)
+	for i := range values {
+		v, ok := m[addr+uint16(i)]
+		if !ok {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func writeRange[T any](m map[uint16]T, addr uint16, values []T) error {
+	for i := range values {
+		if _, ok := m[addr+uint16(i)]; !ok {
+			return modbus.ErrIllegalDataAddress
+		}
+	}
+	for i, v := range values {
+		m[addr+uint16(i)] = v
+	}
+	return nil
+}
+
+// ---
+
+func (c *Controller) SetUnitId(id uint8) error {
+	return nil
+}
+
+func (c *Controller) ReadRegisters(addr uint16, quantity uint16, regType epsolar.RegisterType) ([]uint16, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch regType {
+	case epsolar.HoldingRegister:
+		return readRange(c.holdingRegisters, addr, quantity)
+	case epsolar.InputRegister:
+		return readRange(c.inputRegisters, addr, quantity)
+	default:
+		return nil, fmt.Errorf("unsupported register type: %d", regType)
+	}
+}
+
+func (c *Controller) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return readRange(c.coils, addr, quantity)
+}
+
+func (c *Controller) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return readRange(c.discreteInputs, addr, quantity)
+}
+
+func (c *Controller) WriteRegister(addr uint16, value uint16) error {
+	return c.WriteRegisters(addr, []uint16{value})
+}
+
+func (c *Controller) WriteRegisters(addr uint16, values []uint16) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return writeRange(c.holdingRegisters, addr, values)
+}
+
+func (c *Controller) WriteCoil(addr uint16, value bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return writeRange(c.coils, addr, []bool{value})
+}
+
+func (c *Controller) ReadDeviceIdentification() (map[epsolar.DeviceIdentificationObject]string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.deviceIdentification) == 0 {
+		return nil, modbus.ErrIllegalFunction
+	}
+	return maps.Clone(c.deviceIdentification), nil
+}
+
+// ---
+
+func (c *Controller) HandleCoils(req *modbus.CoilsRequest) ([]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if req.IsWrite {
+		return nil, writeRange(c.coils, req.Addr, req.Args)
+	}
+	return readRange(c.coils, req.Addr, req.Quantity)
+}
+
+func (c *Controller) HandleDiscreteInputs(req *modbus.DiscreteInputsRequest) ([]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return readRange(c.discreteInputs, req.Addr, req.Quantity)
+}
+
+func (c *Controller) HandleHoldingRegisters(req *modbus.HoldingRegistersRequest) ([]uint16, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if req.IsWrite {
+		return nil, writeRange(c.holdingRegisters, req.Addr, req.Args)
+	}
+	return readRange(c.holdingRegisters, req.Addr, req.Quantity)
+}
+
+func (c *Controller) HandleInputRegisters(req *modbus.InputRegistersRequest) ([]uint16, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return readRange(c.inputRegisters, req.Addr, req.Quantity)
+}
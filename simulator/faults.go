@@ -0,0 +1,341 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/simonvetter/modbus"
+)
+
+type Table uint8
+
+const (
+	TableCoils Table = iota
+	TableDiscreteInputs
+	TableHoldingRegisters
+	TableInputRegisters
+)
+
+type FaultKind uint8
+
+const (
+	// FaultTimeout stalls the request for Delay. As a transport, modbus.ErrRequestTimedOut is then returned;
+	// as a server, the (late) response is sent anyway.
+	FaultTimeout FaultKind = iota
+	// FaultBadCRC fails the request with modbus.ErrBadCRC. Modbus TCP has no CRC, so as a server
+	// it is reported as a server device failure exception instead.
+	FaultBadCRC
+	// FaultException fails the request with Err, e.g. modbus.ErrIllegalDataAddress or modbus.ErrServerDeviceBusy.
+	FaultException
+	// FaultStuckValue freezes the registers in range at the values they had when the fault first matched.
+	// Quantity must be set.
+	FaultStuckValue
+	// FaultSetBits sets the bits in Mask on every register in range, e.g. to raise status fault bits.
+	FaultSetBits
+)
+
+// Fault describes a fault to be injected into requests touching a range of a table.
+type Fault struct {
+	Kind     FaultKind
+	Table    Table
+	Addr     uint16
+	Quantity uint16        // 0 matches every address in Table
+	Err      error         // FaultException
+	Delay    time.Duration // FaultTimeout
+	Mask     uint16        // FaultSetBits
+	Start    time.Time     // zero means immediately
+	End      time.Time     // zero means never
+	Count    int           // number of requests to affect, 0 means unlimited
+}
+
+type FaultID int
+
+type injectedFault struct {
+	Fault
+	id          FaultID
+	matched     int
+	stuckValues []uint16
+}
+
+// FaultInjector sits in front of a Controller and injects faults into the requests it serves.
+// Like Controller, it implements both epsolar.Transport and modbus.RequestHandler.
+type FaultInjector struct {
+	mutex      sync.Mutex
+	controller *Controller
+	faults     []*injectedFault
+	nextID     FaultID
+}
+
+func NewFaultInjector(controller *Controller) *FaultInjector {
+	return &FaultInjector{
+		controller: controller,
+	}
+}
+
+// NewServer returns a Modbus server for f listening at url (e.g. tcp://localhost:5020).
+// The server is not started.
+func (f *FaultInjector) NewServer(url string) (*modbus.ModbusServer, error) {
+	return modbus.NewServer(&modbus.ServerConfiguration{
+		URL: url,
+	}, f)
+}
+
+// Inject adds a fault and returns an ID that can be passed to Clear.
+// Faults that would have no effect are rejected.
+func (f *FaultInjector) Inject(fault Fault) (FaultID, error) {
+	switch fault.Kind {
+	case FaultTimeout, FaultBadCRC:
+	case FaultException:
+		if fault.Err == nil {
+			return 0, fmt.Errorf("exception fault requires an error")
+		}
+	case FaultStuckValue, FaultSetBits:
+		if (fault.Table != TableHoldingRegisters) && (fault.Table != TableInputRegisters) {
+			return 0, fmt.Errorf("fault kind %d requires a register table", fault.Kind)
+		}
+		if (fault.Kind == FaultStuckValue) && (fault.Quantity == 0) {
+			return 0, fmt.Errorf("stuck value fault requires a quantity")
+		}
+		if (fault.Kind == FaultSetBits) && (fault.Mask == 0) {
+			return 0, fmt.Errorf("set bits fault requires a mask")
+		}
+	default:
+		return 0, fmt.Errorf("unknown fault kind: %d", fault.Kind)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	f.faults = append(f.faults, &injectedFault{
+		Fault: fault,
+		id:    f.nextID,
+	})
+	return f.nextID, nil
+}
+
+func (f *FaultInjector) Clear(id FaultID) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i, fault := range f.faults {
+		if fault.id == id {
+			f.faults = append(f.faults[:i], f.faults[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *FaultInjector) ClearAll() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.faults = nil
+}
+
+// ---
+
+func (fault *injectedFault) matches(now time.Time, table Table, addr uint16, quantity uint16) bool {
+	if fault.Table != table {
+		return false
+	}
+	if !fault.Start.IsZero() && now.Before(fault.Start) {
+		return false
+	}
+	if !fault.End.IsZero() && !now.Before(fault.End) {
+		return false
+	}
+	if (fault.Count > 0) && (fault.matched >= fault.Count) {
+		return false
+	}
+	if fault.Quantity == 0 {
+		return true
+	}
+	return (int(addr) < int(fault.Addr)+int(fault.Quantity)) && (int(fault.Addr) < int(addr)+int(quantity))
+}
+
+// match returns the faults matching a request, counting the request against each of them.
+func (f *FaultInjector) match(table Table, addr uint16, quantity uint16) []*injectedFault {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	var matched []*injectedFault
+	for _, fault := range f.faults {
+		if fault.matches(now, table, addr, quantity) {
+			fault.matched++
+			matched = append(matched, fault)
+		}
+	}
+	return matched
+}
+
+// before applies the faults that fail or delay a request.
+func (f *FaultInjector) before(faults []*injectedFault, server bool) error {
+	for _, fault := range faults {
+		switch fault.Kind {
+		case FaultTimeout:
+			time.Sleep(fault.Delay)
+			if !server {
+				return modbus.ErrRequestTimedOut
+			}
+		case FaultBadCRC:
+			if server {
+				return modbus.ErrServerDeviceFailure
+			}
+			return modbus.ErrBadCRC
+		case FaultException:
+			return fault.Err
+		}
+	}
+	return nil
+}
+
+// after applies the faults that alter the registers read by a request.
+func (f *FaultInjector) after(faults []*injectedFault, table Table, addr uint16, values []uint16) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, fault := range faults {
+		for i := range values {
+			a := int(addr) + i
+			if (fault.Quantity != 0) && ((a < int(fault.Addr)) || (a >= int(fault.Addr)+int(fault.Quantity))) {
+				continue
+			}
+			switch fault.Kind {
+			case FaultStuckValue:
+				if fault.stuckValues == nil {
+					fault.stuckValues = f.snapshot(fault, table)
+				}
+				if offset := a - int(fault.Addr); offset < len(fault.stuckValues) {
+					values[i] = fault.stuckValues[offset]
+				}
+			case FaultSetBits:
+				values[i] |= fault.Mask
+			}
+		}
+	}
+}
+
+func (f *FaultInjector) snapshot(fault *injectedFault, table Table) []uint16 {
+	registers := f.controller.inputRegisters
+	if table == TableHoldingRegisters {
+		registers = f.controller.holdingRegisters
+	}
+
+	f.controller.mutex.Lock()
+	defer f.controller.mutex.Unlock()
+
+	values := make([]uint16, fault.Quantity)
+	for i := range values {
+		values[i] = registers[fault.Addr+uint16(i)]
+	}
+	return values
+}
+
+func (f *FaultInjector) readRegisters(table Table, addr uint16, quantity uint16, server bool) ([]uint16, error) {
+	faults := f.match(table, addr, quantity)
+	err := f.before(faults, server)
+	if err != nil {
+		return nil, err
+	}
+	regType := epsolar.InputRegister
+	if table == TableHoldingRegisters {
+		regType = epsolar.HoldingRegister
+	}
+	values, err := f.controller.ReadRegisters(addr, quantity, regType)
+	if err != nil {
+		return nil, err
+	}
+	f.after(faults, table, addr, values)
+	return values, nil
+}
+
+// ---
+
+func (f *FaultInjector) SetUnitId(id uint8) error {
+	return f.controller.SetUnitId(id)
+}
+
+func (f *FaultInjector) ReadRegisters(addr uint16, quantity uint16, regType epsolar.RegisterType) ([]uint16, error) {
+	table := TableInputRegisters
+	if regType == epsolar.HoldingRegister {
+		table = TableHoldingRegisters
+	}
+	return f.readRegisters(table, addr, quantity, false)
+}
+
+func (f *FaultInjector) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	err := f.before(f.match(TableCoils, addr, quantity), false)
+	if err != nil {
+		return nil, err
+	}
+	return f.controller.ReadCoils(addr, quantity)
+}
+
+func (f *FaultInjector) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	err := f.before(f.match(TableDiscreteInputs, addr, quantity), false)
+	if err != nil {
+		return nil, err
+	}
+	return f.controller.ReadDiscreteInputs(addr, quantity)
+}
+
+func (f *FaultInjector) WriteRegister(addr uint16, value uint16) error {
+	return f.WriteRegisters(addr, []uint16{value})
+}
+
+func (f *FaultInjector) WriteRegisters(addr uint16, values []uint16) error {
+	err := f.before(f.match(TableHoldingRegisters, addr, uint16(len(values))), false)
+	if err != nil {
+		return err
+	}
+	return f.controller.WriteRegisters(addr, values)
+}
+
+func (f *FaultInjector) WriteCoil(addr uint16, value bool) error {
+	err := f.before(f.match(TableCoils, addr, 1), false)
+	if err != nil {
+		return err
+	}
+	return f.controller.WriteCoil(addr, value)
+}
+
+func (f *FaultInjector) ReadDeviceIdentification() (map[epsolar.DeviceIdentificationObject]string, error) {
+	return f.controller.ReadDeviceIdentification()
+}
+
+// ---
+
+func (f *FaultInjector) HandleCoils(req *modbus.CoilsRequest) ([]bool, error) {
+	err := f.before(f.match(TableCoils, req.Addr, req.Quantity), true)
+	if err != nil {
+		return nil, err
+	}
+	return f.controller.HandleCoils(req)
+}
+
+func (f *FaultInjector) HandleDiscreteInputs(req *modbus.DiscreteInputsRequest) ([]bool, error) {
+	err := f.before(f.match(TableDiscreteInputs, req.Addr, req.Quantity), true)
+	if err != nil {
+		return nil, err
+	}
+	return f.controller.HandleDiscreteInputs(req)
+}
+
+func (f *FaultInjector) HandleHoldingRegisters(req *modbus.HoldingRegistersRequest) ([]uint16, error) {
+	if req.IsWrite {
+		err := f.before(f.match(TableHoldingRegisters, req.Addr, req.Quantity), true)
+		if err != nil {
+			return nil, err
+		}
+		return f.controller.HandleHoldingRegisters(req)
+	}
+	return f.readRegisters(TableHoldingRegisters, req.Addr, req.Quantity, true)
+}
+
+func (f *FaultInjector) HandleInputRegisters(req *modbus.InputRegistersRequest) ([]uint16, error) {
+	return f.readRegisters(TableInputRegisters, req.Addr, req.Quantity, true)
+}
@@ -0,0 +1,457 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ngyewch/epever-solar"
+)
+
+// carbonDioxidePerKWh is the CO2 reduction (t) credited per kWh generated.
+const carbonDioxidePerKWh = 0.000997
+
+type SimulationConfig struct {
+	BatteryRatedVoltageLevel epsolar.BatteryRatedVoltageLevel // 12V or 24V
+	BatteryType              epsolar.BatteryType              // voltage settings keep the sealed lead-acid defaults
+	BatteryCapacity          float64                          // Ah
+	InitialSOC               float64                          // %
+	PVPeakPower              float64                          // W, at solar noon
+	PVVoltage                float64                          // V, maximum power point voltage at full irradiance
+	Sunrise                  time.Duration                    // time of day
+	Sunset                   time.Duration                    // time of day
+	LoadPower                float64                          // W, while the load is on
+	DayLength                time.Duration                    // real time per simulated day
+	Start                    time.Time                        // simulated start time
+}
+
+func (config SimulationConfig) withDefaults() SimulationConfig {
+	if config.BatteryRatedVoltageLevel == epsolar.BatteryRatedVoltageLevelAutoRecognize {
+		config.BatteryRatedVoltageLevel = epsolar.BatteryRatedVoltageLevel12V
+	}
+	if config.BatteryType == epsolar.BatteryTypeUserDefined {
+		config.BatteryType = epsolar.BatteryTypeSealed
+	}
+	if config.BatteryCapacity == 0 {
+		config.BatteryCapacity = 100
+	}
+	if config.InitialSOC == 0 {
+		config.InitialSOC = 50
+	}
+	if config.PVPeakPower == 0 {
+		config.PVPeakPower = 300
+	}
+	if config.PVVoltage == 0 {
+		config.PVVoltage = 36
+	}
+	if config.Sunrise == 0 {
+		config.Sunrise = 6 * time.Hour
+	}
+	if config.Sunset == 0 {
+		config.Sunset = 18 * time.Hour
+	}
+	if config.LoadPower == 0 {
+		config.LoadPower = 20
+	}
+	if config.DayLength == 0 {
+		config.DayLength = 24 * time.Hour
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Now()
+	}
+	return config
+}
+
+// Simulation drives a Tracer AN register image with a PV irradiance curve, a battery model, a load
+// and the boost/float/equalize charging stages. Settings written to the holding registers and
+// coils (e.g. via epsolar.Dev) take effect on the next step.
+type Simulation struct {
+	mutex      sync.Mutex
+	controller *Controller
+	config     SimulationConfig
+	scale      float64
+
+	now              time.Time
+	soc              float64 // 0-1
+	chargingStatus   epsolar.ChargingStatus
+	regulatedFor     time.Duration // time spent at the boost/equalize voltage
+	lastEqualize     time.Time
+	loadDisconnected bool
+	pvVoltage        float64
+	batteryVoltage   float64
+	statistics       simulationStatistics
+}
+
+type simulationStatistics struct {
+	maxPVVoltageToday      float64
+	minPVVoltageToday      float64
+	maxBatteryVoltageToday float64
+	minBatteryVoltageToday float64
+	consumedToday          float64 // kWh
+	consumedThisMonth      float64 // kWh
+	consumedThisYear       float64 // kWh
+	consumedTotal          float64 // kWh
+	generatedToday         float64 // kWh
+	generatedThisMonth     float64 // kWh
+	generatedThisYear      float64 // kWh
+	generatedTotal         float64 // kWh
+}
+
+func NewSimulation(config SimulationConfig) (*Simulation, error) {
+	config = config.withDefaults()
+
+	scale, err := systemScale(config.BatteryRatedVoltageLevel)
+	if err != nil {
+		return nil, err
+	}
+	controller, err := NewTracerAN(config.BatteryRatedVoltageLevel)
+	if err != nil {
+		return nil, err
+	}
+	controller.SetHoldingRegisters(0x9000, uint16(config.BatteryType), u16(config.BatteryCapacity, 1))
+	controller.SetHoldingRegisters(0x903d, uint16(epsolar.LoadControllingModeLightOnOff))
+
+	s := &Simulation{
+		controller:   controller,
+		config:       config,
+		scale:        scale,
+		now:          config.Start,
+		soc:          config.InitialSOC / 100,
+		lastEqualize: config.Start,
+	}
+	s.batteryVoltage = openCircuitVoltage(config.BatteryType, s.soc, s.scale)
+	s.statistics.minPVVoltageToday = math.Inf(1)
+	s.statistics.minBatteryVoltageToday = math.Inf(1)
+	s.step(0)
+
+	return s, nil
+}
+
+// Controller returns the register image updated by the simulation.
+func (s *Simulation) Controller() *Controller {
+	return s.controller
+}
+
+// Now returns the simulated time.
+func (s *Simulation) Now() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.now
+}
+
+// Run advances the simulation every interval of real time until ctx is done.
+// Simulated time advances by interval scaled by 24h / DayLength.
+func (s *Simulation) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	dt := time.Duration(float64(interval) * float64(24*time.Hour) / float64(s.config.DayLength))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Step(dt)
+		}
+	}
+}
+
+// Step advances the simulation by dt of simulated time.
+func (s *Simulation) Step(dt time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.step(dt)
+}
+
+func (s *Simulation) step(dt time.Duration) {
+	prev := s.now
+	s.now = s.now.Add(dt)
+	s.rollOverStatistics(prev)
+
+	c := s.controller
+	hours := dt.Hours()
+
+	// settings
+	boostVoltage := s.holdingVoltage(0x9007)
+	floatVoltage := s.holdingVoltage(0x9008)
+	equalizeVoltage := s.holdingVoltage(0x9006)
+	boostReconnectVoltage := s.holdingVoltage(0x9009)
+	overVoltageDisconnectVoltage := s.holdingVoltage(0x9003)
+	lowVoltageReconnectVoltage := s.holdingVoltage(0x900a)
+	underVoltageWarningVoltage := s.holdingVoltage(0x900c)
+	lowVoltageDisconnectVoltage := s.holdingVoltage(0x900d)
+	nightTimeThresholdVoltage := s.holdingVoltage(0x901e)
+	batteryType := epsolar.BatteryType(s.holding(0x9000))
+	batteryCapacity := float64(s.holding(0x9001))
+	boostDuration := time.Duration(s.holding(0x906c)) * time.Minute
+	equalizeDuration := time.Duration(s.holding(0x906b)) * time.Minute
+	equalizeCycle := time.Duration(s.holding(0x9016)) * 24 * time.Hour
+	chargingEnabled := s.coil(0x0000)
+
+	// PV array
+	irradiance := s.irradiance()
+	pvPower := s.config.PVPeakPower * irradiance
+	s.pvVoltage = 0
+	if irradiance > 0 {
+		s.pvVoltage = s.config.PVVoltage * (0.8 + 0.2*math.Sqrt(irradiance))
+	}
+	night := s.pvVoltage < nightTimeThresholdVoltage
+
+	// battery
+	resistance := internalResistance(s.soc, s.scale, true)
+	ocv := openCircuitVoltage(batteryType, s.soc, s.scale)
+
+	// load
+	loadOn := s.loadOn(night)
+	if s.loadDisconnected {
+		if s.batteryVoltage >= lowVoltageReconnectVoltage {
+			s.loadDisconnected = false
+		}
+	} else if s.batteryVoltage < lowVoltageDisconnectVoltage {
+		s.loadDisconnected = true
+	}
+	loadOn = loadOn && !s.loadDisconnected
+	loadCurrent := 0.0
+	if loadOn {
+		loadCurrent = s.config.LoadPower / s.batteryVoltage
+	}
+
+	// charging
+	chargingCurrent := 0.0
+	if chargingEnabled && (pvPower > 0) {
+		switch s.chargingStatus {
+		case epsolar.ChargingStatusNoCharging:
+			s.regulatedFor = 0
+			if s.batteryVoltage >= boostReconnectVoltage {
+				s.chargingStatus = epsolar.ChargingStatusFloat
+			} else if s.equalizeDue(batteryType, equalizeCycle) {
+				s.chargingStatus = epsolar.ChargingStatusEqualization
+			} else {
+				s.chargingStatus = epsolar.ChargingStatusBoost
+			}
+		case epsolar.ChargingStatusFloat:
+			if s.batteryVoltage < boostReconnectVoltage {
+				s.chargingStatus = epsolar.ChargingStatusBoost
+				s.regulatedFor = 0
+			}
+		}
+
+		targetVoltage := floatVoltage
+		switch s.chargingStatus {
+		case epsolar.ChargingStatusBoost:
+			targetVoltage = boostVoltage
+		case epsolar.ChargingStatusEqualization:
+			targetVoltage = equalizeVoltage
+		}
+
+		maxCurrent := math.Min(pvPower*0.97/s.batteryVoltage, 30)
+		regulatedCurrent := math.Max((targetVoltage-ocv)/resistance+loadCurrent, 0)
+		chargingCurrent = maxCurrent
+		if regulatedCurrent <= maxCurrent {
+			chargingCurrent = regulatedCurrent
+			s.regulatedFor += dt
+		}
+
+		switch {
+		case (s.chargingStatus == epsolar.ChargingStatusBoost) && (s.regulatedFor >= boostDuration):
+			s.chargingStatus = epsolar.ChargingStatusFloat
+		case (s.chargingStatus == epsolar.ChargingStatusEqualization) && (s.regulatedFor >= equalizeDuration):
+			s.chargingStatus = epsolar.ChargingStatusFloat
+			s.lastEqualize = s.now
+		}
+	} else {
+		s.chargingStatus = epsolar.ChargingStatusNoCharging
+		pvPower = 0
+	}
+
+	batteryCurrent := chargingCurrent - loadCurrent
+	if batteryCapacity > 0 {
+		s.soc = math.Max(0, math.Min(1, s.soc+batteryCurrent*hours/batteryCapacity))
+	}
+	ocv = openCircuitVoltage(batteryType, s.soc, s.scale)
+	s.batteryVoltage = ocv + batteryCurrent*internalResistance(s.soc, s.scale, batteryCurrent > 0)
+	chargingPower := chargingCurrent * s.batteryVoltage
+	if chargingPower > 0 {
+		pvPower = chargingPower / 0.97
+	}
+	pvCurrent := 0.0
+	if s.pvVoltage > 0 {
+		pvCurrent = pvPower / s.pvVoltage
+	}
+	loadVoltage := 0.0
+	if loadOn {
+		loadVoltage = s.batteryVoltage
+	}
+	loadPower := loadVoltage * loadCurrent
+	batteryTemperature := 20 + 10*irradiance
+	deviceTemperature := batteryTemperature + 0.3*chargingCurrent
+	powerComponentsTemperature := batteryTemperature + 0.6*chargingCurrent
+
+	// statistics
+	st := &s.statistics
+	st.generatedToday += chargingPower * hours / 1000
+	st.generatedThisMonth += chargingPower * hours / 1000
+	st.generatedThisYear += chargingPower * hours / 1000
+	st.generatedTotal += chargingPower * hours / 1000
+	st.consumedToday += loadPower * hours / 1000
+	st.consumedThisMonth += loadPower * hours / 1000
+	st.consumedThisYear += loadPower * hours / 1000
+	st.consumedTotal += loadPower * hours / 1000
+	st.maxPVVoltageToday = math.Max(st.maxPVVoltageToday, s.pvVoltage)
+	st.minPVVoltageToday = math.Min(st.minPVVoltageToday, s.pvVoltage)
+	st.maxBatteryVoltageToday = math.Max(st.maxBatteryVoltageToday, s.batteryVoltage)
+	st.minBatteryVoltageToday = math.Min(st.minBatteryVoltageToday, s.batteryVoltage)
+
+	// real-time data
+	c.SetInputRegisters(0x3100, u16(s.pvVoltage, 100), u16(pvCurrent, 100))
+	c.SetInputRegisterUint32(0x3102, u32(pvPower, 100))
+	c.SetInputRegisters(0x3104, u16(s.batteryVoltage, 100), u16(chargingCurrent, 100))
+	c.SetInputRegisterUint32(0x3106, u32(chargingPower, 100))
+	c.SetInputRegisters(0x310c, u16(loadVoltage, 100), u16(loadCurrent, 100))
+	c.SetInputRegisterUint32(0x310e, u32(loadPower, 100))
+	c.SetInputRegisters(0x3110, i16(batteryTemperature, 100), i16(deviceTemperature, 100), i16(powerComponentsTemperature, 100))
+	c.SetInputRegisters(0x311a, u16(s.soc*100, 1), i16(batteryTemperature, 100))
+	c.SetInputRegisters(0x331a, u16(s.batteryVoltage, 100))
+	c.SetInputRegisterUint32(0x331b, u32(batteryCurrent, 100))
+
+	// real-time status
+	voltageStatus := epsolar.VoltageStatusNormal
+	switch {
+	case s.batteryVoltage > overVoltageDisconnectVoltage:
+		voltageStatus = epsolar.VoltageStatusOverVoltage
+	case s.batteryVoltage < lowVoltageDisconnectVoltage:
+		voltageStatus = epsolar.VoltageStatusOverDischarge
+	case s.batteryVoltage < underVoltageWarningVoltage:
+		voltageStatus = epsolar.VoltageStatusUnderVoltage
+	}
+	chargingEquipmentStatus := uint16(s.chargingStatus) << 2
+	if chargingEnabled {
+		chargingEquipmentStatus |= 0x0001 // running
+	}
+	if s.pvVoltage == 0 {
+		chargingEquipmentStatus |= uint16(epsolar.InputVoltageStatusNoInputPowerConnected) << 14
+	}
+	var dischargingEquipmentStatus uint16
+	if loadOn {
+		dischargingEquipmentStatus |= 0x0001 // running
+	}
+	c.SetInputRegisters(0x3200, uint16(voltageStatus), chargingEquipmentStatus, dischargingEquipmentStatus)
+	c.SetDiscreteInputs(0x200c, night)
+
+	// statistics
+	c.SetInputRegisters(0x3300,
+		u16(st.maxPVVoltageToday, 100),
+		u16(st.minPVVoltageToday, 100),
+		u16(st.maxBatteryVoltageToday, 100),
+		u16(st.minBatteryVoltageToday, 100),
+	)
+	c.SetInputRegisterUint32(0x3304, u32(st.consumedToday, 100))
+	c.SetInputRegisterUint32(0x3306, u32(st.consumedThisMonth, 100))
+	c.SetInputRegisterUint32(0x3308, u32(st.consumedThisYear, 100))
+	c.SetInputRegisterUint32(0x330a, u32(st.consumedTotal, 100))
+	c.SetInputRegisterUint32(0x330c, u32(st.generatedToday, 100))
+	c.SetInputRegisterUint32(0x330e, u32(st.generatedThisMonth, 100))
+	c.SetInputRegisterUint32(0x3310, u32(st.generatedThisYear, 100))
+	c.SetInputRegisterUint32(0x3312, u32(st.generatedTotal, 100))
+	c.SetInputRegisterUint32(0x3314, u32(st.generatedTotal*carbonDioxidePerKWh, 100))
+
+	// real-time clock
+	c.SetHoldingRegisters(0x9013,
+		uint16(s.now.Minute())<<8|uint16(s.now.Second()),
+		uint16(s.now.Day())<<8|uint16(s.now.Hour()),
+		uint16(s.now.Year()%100)<<8|uint16(s.now.Month()),
+	)
+}
+
+func (s *Simulation) rollOverStatistics(prev time.Time) {
+	st := &s.statistics
+	if (s.now.Year() != prev.Year()) || (s.now.YearDay() != prev.YearDay()) {
+		st.consumedToday = 0
+		st.generatedToday = 0
+		st.maxPVVoltageToday = 0
+		st.minPVVoltageToday = math.Inf(1)
+		st.maxBatteryVoltageToday = 0
+		st.minBatteryVoltageToday = math.Inf(1)
+	}
+	if (s.now.Year() != prev.Year()) || (s.now.Month() != prev.Month()) {
+		st.consumedThisMonth = 0
+		st.generatedThisMonth = 0
+	}
+	if s.now.Year() != prev.Year() {
+		st.consumedThisYear = 0
+		st.generatedThisYear = 0
+	}
+}
+
+// irradiance returns the PV irradiance (0-1) at the current simulated time.
+func (s *Simulation) irradiance() float64 {
+	year, month, day := s.now.Date()
+	timeOfDay := s.now.Sub(time.Date(year, month, day, 0, 0, 0, 0, s.now.Location()))
+	if (timeOfDay <= s.config.Sunrise) || (timeOfDay >= s.config.Sunset) {
+		return 0
+	}
+	return math.Sin(math.Pi * float64(timeOfDay-s.config.Sunrise) / float64(s.config.Sunset-s.config.Sunrise))
+}
+
+func (s *Simulation) loadOn(night bool) bool {
+	if s.coil(0x0005) {
+		return s.coil(0x0006)
+	}
+	switch epsolar.LoadControllingMode(s.holding(0x903d)) {
+	case epsolar.LoadControllingModeManual:
+		return s.coil(0x0002)
+	case epsolar.LoadControllingModeLightOnOff, epsolar.LoadControllingModeLightOnTimer:
+		return night
+	case epsolar.LoadControllingModeTimeControl:
+		on := s.timeOfDayHolding(0x9042)
+		off := s.timeOfDayHolding(0x9045)
+		t := s.now.Sub(time.Date(s.now.Year(), s.now.Month(), s.now.Day(), 0, 0, 0, 0, s.now.Location()))
+		if on <= off {
+			return (t >= on) && (t < off)
+		}
+		return (t >= on) || (t < off)
+	default:
+		return false
+	}
+}
+
+func (s *Simulation) equalizeDue(batteryType epsolar.BatteryType, equalizeCycle time.Duration) bool {
+	switch batteryType {
+	case epsolar.BatteryTypeSealed, epsolar.BatteryTypeFlooded:
+		return (equalizeCycle > 0) && (s.now.Sub(s.lastEqualize) >= equalizeCycle)
+	default:
+		return false
+	}
+}
+
+func (s *Simulation) holding(addr uint16) uint16 {
+	v, _ := s.controller.HoldingRegister(addr)
+	return v
+}
+
+func (s *Simulation) holdingVoltage(addr uint16) float64 {
+	return float64(s.holding(addr)) / 100
+}
+
+func (s *Simulation) timeOfDayHolding(addr uint16) time.Duration {
+	return time.Duration(s.holding(addr+2))*time.Hour +
+		time.Duration(s.holding(addr+1))*time.Minute +
+		time.Duration(s.holding(addr))*time.Second
+}
+
+func (s *Simulation) coil(addr uint16) bool {
+	v, _ := s.controller.Coil(addr)
+	return v
+}
+
+func (s *Simulation) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return fmt.Sprintf("%s soc=%.1f%% battery=%.2fV pv=%.2fV stage=%s",
+		s.now.Format(time.DateTime), s.soc*100, s.batteryVoltage, s.pvVoltage, s.chargingStatus)
+}
@@ -0,0 +1,167 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ngyewch/epever-solar"
+)
+
+// NewTracerAN returns a Controller populated with the register image of a 30A Tracer AN series
+// controller (e.g. Tracer3210AN) running a sealed lead-acid battery at the given system voltage.
+// Only 12V and 24V systems are supported.
+func NewTracerAN(level epsolar.BatteryRatedVoltageLevel) (*Controller, error) {
+	scale, err := systemScale(level)
+	if err != nil {
+		return nil, err
+	}
+
+	c := New()
+
+	c.SetDeviceIdentification(map[epsolar.DeviceIdentificationObject]string{
+		epsolar.DeviceIdentificationVendorName:         "EPEVER",
+		epsolar.DeviceIdentificationProductCode:        "Tracer3210AN",
+		epsolar.DeviceIdentificationMajorMinorRevision: "V02.13",
+		epsolar.DeviceIdentificationModelName:          "Tracer3210AN",
+	})
+
+	// rated data
+	c.SetInputRegisters(0x3000, make([]uint16, 0x11)...)
+	c.SetInputRegisters(0x3000, 10000, 3000)
+	c.SetInputRegisterUint32(0x3002, uint32(39000*scale))
+	c.SetInputRegisters(0x3004, u16(12*scale, 100), 3000)
+	c.SetInputRegisterUint32(0x3006, uint32(39000*scale))
+	c.SetInputRegisters(0x300d, u16(12*scale, 100), 3000)
+	c.SetInputRegisterUint32(0x300f, uint32(39000*scale))
+
+	// real-time data
+	pvVoltage := 18 * scale
+	pvCurrent := 5.2
+	pvPower := pvVoltage * pvCurrent
+	batteryVoltage := 13.2 * scale
+	loadCurrent := 1.5
+	loadPower := batteryVoltage * loadCurrent
+	chargingCurrent := pvPower / batteryVoltage
+	batteryCurrent := chargingCurrent - loadCurrent
+	c.SetInputRegisters(0x3100, make([]uint16, 0x1e)...)
+	c.SetInputRegisters(0x3100, u16(pvVoltage, 100), u16(pvCurrent, 100))
+	c.SetInputRegisterUint32(0x3102, u32(pvPower, 100))
+	c.SetInputRegisters(0x3104, u16(batteryVoltage, 100), u16(chargingCurrent, 100))
+	c.SetInputRegisterUint32(0x3106, u32(pvPower, 100))
+	c.SetInputRegisters(0x310c, u16(batteryVoltage, 100), u16(loadCurrent, 100))
+	c.SetInputRegisterUint32(0x310e, u32(loadPower, 100))
+	c.SetInputRegisters(0x3110, i16(25, 100), i16(28, 100), i16(30, 100))
+	c.SetInputRegisters(0x311a, 75, i16(25, 100))
+	c.SetInputRegisters(0x311d, u16(12*scale, 100))
+
+	// real-time status
+	c.SetInputRegisters(0x3200,
+		0x0000, // battery status: normal
+		0x0009, // charging equipment status: running, boost
+		0x0001, // discharging equipment status: running
+	)
+
+	// statistics
+	c.SetInputRegisters(0x3300, make([]uint16, 0x1d)...)
+	c.SetInputRegisters(0x3300, u16(21*scale, 100), 0, u16(14.4*scale, 100), u16(12.4*scale, 100))
+	c.SetInputRegisterUint32(0x3304, 24)
+	c.SetInputRegisterUint32(0x3306, 720)
+	c.SetInputRegisterUint32(0x3308, 8640)
+	c.SetInputRegisterUint32(0x330a, 17280)
+	c.SetInputRegisterUint32(0x330c, uint32(60*scale))
+	c.SetInputRegisterUint32(0x330e, uint32(1800*scale))
+	c.SetInputRegisterUint32(0x3310, uint32(21600*scale))
+	c.SetInputRegisterUint32(0x3312, uint32(43200*scale))
+	c.SetInputRegisterUint32(0x3314, u32(432*scale*carbonDioxidePerKWh, 100))
+	c.SetInputRegisters(0x331a, u16(batteryVoltage, 100))
+	c.SetInputRegisterUint32(0x331b, u32(batteryCurrent, 100))
+
+	// discrete inputs
+	c.SetDiscreteInputs(0x2000, false)
+	c.SetDiscreteInputs(0x200c, false)
+
+	// coils
+	c.SetCoils(0x0000, true, false, false, false)
+	c.SetCoils(0x0005, false, false)
+	c.SetCoils(0x0013, false, false)
+
+	// battery voltage settings (sealed)
+	c.SetHoldingRegisters(0x9000,
+		uint16(epsolar.BatteryTypeSealed),
+		200, // Ah
+		300, // mV/°C/2V
+		u16(16.0*scale, 100),
+		u16(15.0*scale, 100),
+		u16(15.0*scale, 100),
+		u16(14.6*scale, 100),
+		u16(14.4*scale, 100),
+		u16(13.8*scale, 100),
+		u16(13.2*scale, 100),
+		u16(12.6*scale, 100),
+		u16(12.2*scale, 100),
+		u16(12.0*scale, 100),
+		u16(11.1*scale, 100),
+		u16(10.6*scale, 100),
+	)
+
+	// real-time clock (2024-01-01 00:00:00)
+	c.SetHoldingRegisters(0x9013, 0x0000, 0x0100, 0x1801)
+
+	// temperature limits
+	c.SetHoldingRegisters(0x9016,
+		30,            // equalize charging cycle (days)
+		u16(65, 100),  // battery temperature warning upper limit
+		i16(-40, 100), // battery temperature warning lower limit
+		u16(85, 100),  // controller inner temperature upper limit
+		u16(75, 100),  // controller inner temperature upper limit recover
+	)
+
+	// light control
+	c.SetHoldingRegisters(0x901e, u16(5*scale, 100), 10, u16(6*scale, 100), 10)
+
+	// load control
+	c.SetHoldingRegisters(0x903d, uint16(epsolar.LoadControllingModeManual), 0x0100, 0x0100)
+	c.SetHoldingRegisters(0x9042,
+		0, 0, 19, // turn on timing 1
+		0, 0, 6, // turn off timing 1
+		0, 0, 19, // turn on timing 2
+		0, 0, 6, // turn off timing 2
+	)
+	c.SetHoldingRegisters(0x9065, 0x0a00)
+	c.SetHoldingRegisters(0x9067, uint16(level))
+	c.SetHoldingRegisters(0x906a,
+		1,     // default load on/off in manual mode
+		120,   // equalize duration
+		120,   // boost duration
+		3000,  // battery discharge
+		10000, // battery charge
+	)
+	c.SetHoldingRegisters(0x9070, uint16(epsolar.ChargingModeVoltageCompensation))
+	c.SetHoldingRegisters(0x9107, 0x0000)
+
+	return c, nil
+}
+
+// systemScale returns the number of 12V blocks in a system of the given voltage level.
+func systemScale(level epsolar.BatteryRatedVoltageLevel) (float64, error) {
+	switch level {
+	case epsolar.BatteryRatedVoltageLevel12V:
+		return 1, nil
+	case epsolar.BatteryRatedVoltageLevel24V:
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("unsupported battery rated voltage level: %s", level)
+	}
+}
+
+func u16(v float64, multiplier float64) uint16 {
+	return uint16(math.Round(v * multiplier))
+}
+
+func u32(v float64, multiplier float64) uint32 {
+	return uint32(int32(math.Round(v * multiplier)))
+}
+
+func i16(v float64, multiplier float64) uint16 {
+	return uint16(int16(math.Round(v * multiplier)))
+}
@@ -13,4 +13,5 @@ type Statistics struct {
 	GeneratedEnergyThisMonth   *float64 // kWh
 	GeneratedEnergyThisYear    *float64 // kWh
 	TotalGeneratedEnergy       *float64 // kWh
+	CarbonDioxideReduction     *float64 // t
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doEpsolarChargingEnable(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.SetChargingDeviceContext(ctx, true)
+}
+
+func doEpsolarChargingDisable(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.SetChargingDeviceContext(ctx, false)
+}
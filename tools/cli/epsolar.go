@@ -12,13 +12,72 @@ import (
 	"github.com/urfave/cli/v3"
 )
 
+func doEpsolarInfo(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDevWithDeviceIdentification(ctx, cmd, true)
+	if err != nil {
+		return err
+	}
+
+	deviceInfo, err := dev.ReadDeviceInfoContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = dump(deviceInfo)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func doEpsolarProfile(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	profile := dev.Profile()
+	if profile == nil {
+		profile, err = dev.SelectProfileContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var supported []string
+	var unsupported []string
+	for _, group := range epsolar.RegisterGroupAll.Groups() {
+		if profile.Supports(group) {
+			supported = append(supported, group.String())
+		} else {
+			unsupported = append(unsupported, group.String())
+		}
+	}
+
+	err = dump(struct {
+		Name        string
+		Supported   []string
+		Unsupported []string
+	}{
+		Name:        profile.Name,
+		Supported:   supported,
+		Unsupported: unsupported,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func doEpsolarRatedData(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	ratedData, err := dev.ReadRatedData()
+	ratedData, err := dev.ReadRatedDataContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -32,12 +91,12 @@ func doEpsolarRatedData(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarParameters(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	parameters, err := dev.ReadParameters()
+	parameters, err := dev.ReadParametersContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -51,12 +110,12 @@ func doEpsolarParameters(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarRealTimeData(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	realTimeData, err := dev.ReadRealTimeData()
+	realTimeData, err := dev.ReadRealTimeDataContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -70,12 +129,12 @@ func doEpsolarRealTimeData(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarRealTimeStatus(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	realTimeStatus, err := dev.ReadRealTimeStatus()
+	realTimeStatus, err := dev.ReadRealTimeStatusContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -89,12 +148,12 @@ func doEpsolarRealTimeStatus(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarStatistics(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	statistics, err := dev.ReadStatistics()
+	statistics, err := dev.ReadStatisticsContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -108,7 +167,7 @@ func doEpsolarStatistics(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarPrometheus(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
@@ -144,12 +203,12 @@ func doEpsolarPrometheus(ctx context.Context, cmd *cli.Command) error {
 }
 
 func doEpsolarRTCGet(ctx context.Context, cmd *cli.Command) error {
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	rtc, err := dev.ReadRealTimeClock()
+	rtc, err := dev.ReadRealTimeClockContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -186,12 +245,12 @@ func doEpsolarRTCSet(ctx context.Context, cmd *cli.Command) error {
 		Second: uint8(t.Second()),
 	}
 
-	dev, err := newDev(cmd)
+	dev, err := newDev(ctx, cmd)
 	if err != nil {
 		return err
 	}
 
-	err = dev.SetRealTimeClock(rtcData)
+	err = dev.SetRealTimeClockContext(ctx, rtcData)
 	if err != nil {
 		return err
 	}
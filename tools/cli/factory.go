@@ -1,28 +1,118 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
 	"sync"
 
 	epsolar "github.com/ngyewch/epever-solar"
+	"github.com/simonvetter/modbus"
 	"github.com/urfave/cli/v3"
 )
 
-func newDev(cmd *cli.Command) (*epsolar.Dev, error) {
-	client, err := newModbusClient(cmd, nil)
+func newDev(ctx context.Context, cmd *cli.Command) (*epsolar.Dev, error) {
+	return newDevWithDeviceIdentification(ctx, cmd, cmd.Bool(deviceIdentificationFlag.Name))
+}
+
+// newDevWithDeviceIdentification is newDev, enabling device identification on Modbus clients if identify is
+// true.
+func newDevWithDeviceIdentification(ctx context.Context, cmd *cli.Command, identify bool) (*epsolar.Dev, error) {
+	transport, err := newTransport(cmd, identify)
 	if err != nil {
 		return nil, err
 	}
 
 	modbusUnitId := cmd.Uint(modbusUnitIdFlag.Name)
 
-	err = client.Open()
-	if err != nil {
-		return nil, err
-	}
-
 	var mutex sync.Mutex
 
-	dev := epsolar.New(client, uint8(modbusUnitId), &mutex)
+	dev := epsolar.NewWithTransport(transport, uint8(modbusUnitId), &mutex)
+	dev.SetRetryPolicy(epsolar.RetryPolicy{
+		MaxAttempts:    int(cmd.Uint(retryAttemptsFlag.Name)),
+		InitialBackoff: cmd.Duration(retryBackoffFlag.Name),
+		MaxBackoff:     cmd.Duration(retryMaxBackoffFlag.Name),
+		Multiplier:     2,
+	})
+	dev.SetInterFrameDelay(cmd.Duration(interFrameDelayFlag.Name))
+
+	switch profileName := cmd.String(profileFlag.Name); profileName {
+	case "":
+	case "auto":
+		_, err = dev.SelectProfileContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		profile, err := parseProfile(profileName)
+		if err != nil {
+			return nil, err
+		}
+		dev.SetProfile(profile)
+	}
 
 	return dev, nil
 }
+
+func newTransport(cmd *cli.Command, identify bool) (epsolar.Transport, error) {
+	replayFile := cmd.String(replayFlag.Name)
+	recordFile := cmd.String(recordFlag.Name)
+
+	var transport epsolar.Transport
+	if replayFile != "" {
+		f, err := os.Open(replayFile)
+		if err != nil {
+			return nil, err
+		}
+		defer func(f *os.File) {
+			_ = f.Close()
+		}(f)
+
+		transport, err = epsolar.NewReplayTransport(f)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		config, err := newModbusClientConfiguration(cmd, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := modbus.NewClient(config)
+		if err != nil {
+			return nil, err
+		}
+
+		err = client.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		if identify {
+			transport = epsolar.NewModbusClientTransportWithConfiguration(client, config)
+		} else {
+			transport = epsolar.NewModbusClientTransport(client)
+		}
+	}
+
+	if recordFile != "" {
+		f, err := os.OpenFile(recordFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		transport = epsolar.NewRecordingTransport(transport, f)
+	}
+
+	return transport, nil
+}
+
+func parseProfile(s string) (*epsolar.Profile, error) {
+	for _, profile := range epsolar.Profiles {
+		if strings.EqualFold(profile.Name, s) {
+			return profile, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid profile: %s", s)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doEpsolarLoadStatus(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	loadControl, err := dev.ReadLoadControlContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = dump(loadControl)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func doEpsolarLoadOn(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.SetManualLoadControlContext(ctx, true)
+}
+
+func doEpsolarLoadOff(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.SetManualLoadControlContext(ctx, false)
+}
+
+func doEpsolarLoadTest(ctx context.Context, cmd *cli.Command) error {
+	disable := cmd.Bool(loadTestDisableFlag.Name)
+
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if disable {
+		err = dev.ForceLoadContext(ctx, false)
+		if err != nil {
+			return err
+		}
+		return dev.SetLoadTestModeContext(ctx, false)
+	}
+
+	err = dev.SetLoadTestModeContext(ctx, true)
+	if err != nil {
+		return err
+	}
+	return dev.ForceLoadContext(ctx, true)
+}
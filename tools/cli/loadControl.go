@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/urfave/cli/v3"
+)
+
+func parseLoadControllingMode(s string) (epsolar.LoadControllingMode, error) {
+	switch strings.ToLower(s) {
+	case "manual":
+		return epsolar.LoadControllingModeManual, nil
+	case "light-on-off":
+		return epsolar.LoadControllingModeLightOnOff, nil
+	case "light-on-timer":
+		return epsolar.LoadControllingModeLightOnTimer, nil
+	case "time-control":
+		return epsolar.LoadControllingModeTimeControl, nil
+	}
+	return 0, fmt.Errorf("invalid load controlling mode: %s", s)
+}
+
+func doEpsolarLoadControlGet(ctx context.Context, cmd *cli.Command) error {
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	settings, err := dev.ReadLoadControlSettingsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = dump(settings)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func doEpsolarLoadControlSet(ctx context.Context, cmd *cli.Command) error {
+	var settings epsolar.LoadControlSettings
+
+	if cmd.IsSet(loadControllingModeFlag.Name) {
+		mode, err := parseLoadControllingMode(cmd.String(loadControllingModeFlag.Name))
+		if err != nil {
+			return err
+		}
+		settings.LoadControllingMode = &mode
+	}
+	settings.WorkingTimeLength1 = durationFlagValue(cmd, workingTimeLength1Flag)
+	settings.WorkingTimeLength2 = durationFlagValue(cmd, workingTimeLength2Flag)
+	settings.LengthOfNight = durationFlagValue(cmd, lengthOfNightFlag)
+	for _, entry := range []struct {
+		flag *cli.StringFlag
+		dest **epsolar.TimeOfDay
+	}{
+		{turnOnTiming1Flag, &settings.TurnOnTiming1},
+		{turnOffTiming1Flag, &settings.TurnOffTiming1},
+		{turnOnTiming2Flag, &settings.TurnOnTiming2},
+		{turnOffTiming2Flag, &settings.TurnOffTiming2},
+	} {
+		if !cmd.IsSet(entry.flag.Name) {
+			continue
+		}
+		t, err := epsolar.ParseTimeOfDay(cmd.String(entry.flag.Name))
+		if err != nil {
+			return err
+		}
+		*entry.dest = &t
+	}
+
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.WriteLoadControlSettingsContext(ctx, settings)
+}
+
+func durationFlagValue(cmd *cli.Command, flag *cli.DurationFlag) *time.Duration {
+	if !cmd.IsSet(flag.Name) {
+		return nil
+	}
+	d := cmd.Duration(flag.Name)
+	return &d
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
@@ -15,8 +16,7 @@ var (
 
 	serialPortFlag = &cli.StringFlag{
 		Name:     "serial-port",
-		Usage:    "serial port",
-		Required: true,
+		Usage:    "serial port (required unless --url is specified)",
 		Sources:  cli.EnvVars("SERIAL_PORT"),
 		Category: "Serial",
 	}
@@ -52,6 +52,33 @@ var (
 		Sources:  cli.EnvVars("STOP_BITS"),
 		Category: "Serial",
 	}
+	urlFlag = &cli.StringFlag{
+		Name:    "url",
+		Usage:   "Modbus URL (tcp://, tcp+tls://, udp://, rtuovertcp://, rtuoverudp://, rtu://), overrides --serial-port",
+		Sources: cli.EnvVars("MODBUS_URL"),
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			return validateModbusURL(s)
+		},
+		Category: "Modbus",
+	}
+	tlsClientCertFlag = &cli.StringFlag{
+		Name:     "tls-client-cert",
+		Usage:    "TLS client certificate file (tcp+tls only)",
+		Sources:  cli.EnvVars("TLS_CLIENT_CERT"),
+		Category: "TLS",
+	}
+	tlsClientKeyFlag = &cli.StringFlag{
+		Name:     "tls-client-key",
+		Usage:    "TLS client key file (tcp+tls only)",
+		Sources:  cli.EnvVars("TLS_CLIENT_KEY"),
+		Category: "TLS",
+	}
+	tlsRootCAsFlag = &cli.StringFlag{
+		Name:     "tls-root-cas",
+		Usage:    "TLS root CA certificates file (tcp+tls only)",
+		Sources:  cli.EnvVars("TLS_ROOT_CAS"),
+		Category: "TLS",
+	}
 	modbusUnitIdFlag = &cli.UintFlag{
 		Name:    "modbus-unit-id",
 		Usage:   "ModBus unit ID",
@@ -65,6 +92,159 @@ var (
 		},
 		Category: "Modbus",
 	}
+	timeoutFlag = &cli.DurationFlag{
+		Name:     "timeout",
+		Usage:    "Modbus request timeout",
+		Value:    1 * time.Second,
+		Sources:  cli.EnvVars("TIMEOUT"),
+		Category: "Modbus",
+	}
+	retryAttemptsFlag = &cli.UintFlag{
+		Name:     "retry-attempts",
+		Usage:    "maximum attempts per Modbus request (1 disables retries)",
+		Value:    3,
+		Sources:  cli.EnvVars("RETRY_ATTEMPTS"),
+		Category: "Modbus",
+	}
+	retryBackoffFlag = &cli.DurationFlag{
+		Name:     "retry-backoff",
+		Usage:    "delay before the first retry, doubled on each subsequent retry",
+		Value:    100 * time.Millisecond,
+		Sources:  cli.EnvVars("RETRY_BACKOFF"),
+		Category: "Modbus",
+	}
+	retryMaxBackoffFlag = &cli.DurationFlag{
+		Name:     "retry-max-backoff",
+		Usage:    "maximum delay between retries",
+		Value:    1 * time.Second,
+		Sources:  cli.EnvVars("RETRY_MAX_BACKOFF"),
+		Category: "Modbus",
+	}
+	interFrameDelayFlag = &cli.DurationFlag{
+		Name:     "inter-frame-delay",
+		Usage:    "minimum gap between Modbus requests",
+		Sources:  cli.EnvVars("INTER_FRAME_DELAY"),
+		Category: "Modbus",
+	}
+	profileFlag = &cli.StringFlag{
+		Name:    "profile",
+		Usage:   "profile used to skip unsupported registers (auto to probe the controller, or generic)",
+		Sources: cli.EnvVars("PROFILE"),
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			if s == "auto" {
+				return nil
+			}
+			_, err := parseProfile(s)
+			return err
+		},
+		Category: "Modbus",
+	}
+	deviceIdentificationFlag = &cli.BoolFlag{
+		Name:     "device-identification",
+		Usage:    "read device identification (function 0x2B/0x0E), closing and reopening the Modbus connection once; always enabled for info",
+		Sources:  cli.EnvVars("DEVICE_IDENTIFICATION"),
+		Category: "Modbus",
+	}
+	recordFlag = &cli.StringFlag{
+		Name:     "record",
+		Usage:    "append Modbus transactions to JSON Lines file",
+		Sources:  cli.EnvVars("RECORD"),
+		Category: "Modbus",
+	}
+	replayFlag = &cli.StringFlag{
+		Name:     "replay",
+		Usage:    "replay Modbus transactions from JSON Lines file instead of using the serial port",
+		Sources:  cli.EnvVars("REPLAY"),
+		Category: "Modbus",
+	}
+	loadTestDisableFlag = &cli.BoolFlag{
+		Name:  "disable",
+		Usage: "force load off and leave load test mode",
+	}
+	loadControllingModeFlag = &cli.StringFlag{
+		Name:  "mode",
+		Usage: "load controlling mode (manual, light-on-off, light-on-timer, time-control)",
+		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
+			_, err := parseLoadControllingMode(s)
+			return err
+		},
+	}
+	workingTimeLength1Flag = &cli.DurationFlag{
+		Name:  "working-time-length-1",
+		Usage: "working time length 1",
+	}
+	workingTimeLength2Flag = &cli.DurationFlag{
+		Name:  "working-time-length-2",
+		Usage: "working time length 2",
+	}
+	turnOnTiming1Flag = &cli.StringFlag{
+		Name:  "turn-on-timing-1",
+		Usage: "turn on timing 1 (hh:mm[:ss])",
+	}
+	turnOffTiming1Flag = &cli.StringFlag{
+		Name:  "turn-off-timing-1",
+		Usage: "turn off timing 1 (hh:mm[:ss])",
+	}
+	turnOnTiming2Flag = &cli.StringFlag{
+		Name:  "turn-on-timing-2",
+		Usage: "turn on timing 2 (hh:mm[:ss])",
+	}
+	turnOffTiming2Flag = &cli.StringFlag{
+		Name:  "turn-off-timing-2",
+		Usage: "turn off timing 2 (hh:mm[:ss])",
+	}
+	lengthOfNightFlag = &cli.DurationFlag{
+		Name:  "length-of-night",
+		Usage: "length of night",
+	}
+	listenFlag = &cli.StringFlag{
+		Name:  "listen",
+		Usage: "Modbus TCP listen URL",
+		Value: "tcp://localhost:5020",
+	}
+	simulationBatteryRatedVoltageFlag = &cli.UintFlag{
+		Name:  "battery-rated-voltage",
+		Usage: "battery rated voltage (12 or 24)",
+		Value: 12,
+	}
+	simulationBatteryCapacityFlag = &cli.FloatFlag{
+		Name:  "battery-capacity",
+		Usage: "battery capacity (Ah)",
+		Value: 100,
+	}
+	simulationInitialSOCFlag = &cli.FloatFlag{
+		Name:  "initial-soc",
+		Usage: "initial battery state of charge (%)",
+		Value: 50,
+	}
+	simulationPVPeakPowerFlag = &cli.FloatFlag{
+		Name:  "pv-peak-power",
+		Usage: "PV peak power (W)",
+		Value: 300,
+	}
+	simulationLoadPowerFlag = &cli.FloatFlag{
+		Name:  "load-power",
+		Usage: "load power (W)",
+		Value: 20,
+	}
+	simulationDayLengthFlag = &cli.DurationFlag{
+		Name:  "day-length",
+		Usage: "real time per simulated day",
+		Value: 24 * time.Hour,
+	}
+	simulationIntervalFlag = &cli.DurationFlag{
+		Name:  "interval",
+		Usage: "simulation update interval",
+		Value: 1 * time.Second,
+	}
+	yesFlag = &cli.BoolFlag{
+		Name:  "yes",
+		Usage: "confirm operation",
+	}
+	backupFileFlag = &cli.StringFlag{
+		Name:  "backup-file",
+		Usage: "parameter backup file (default: epsolar-parameters-<timestamp>.json)",
+	}
 
 	app = &cli.Command{
 		Name:  "epsolar",
@@ -75,9 +255,32 @@ var (
 			dataBitsFlag,
 			parityFlag,
 			stopBitsFlag,
+			urlFlag,
+			tlsClientCertFlag,
+			tlsClientKeyFlag,
+			tlsRootCAsFlag,
 			modbusUnitIdFlag,
+			timeoutFlag,
+			retryAttemptsFlag,
+			retryBackoffFlag,
+			retryMaxBackoffFlag,
+			interFrameDelayFlag,
+			profileFlag,
+			deviceIdentificationFlag,
+			recordFlag,
+			replayFlag,
 		},
 		Commands: []*cli.Command{
+			{
+				Name:   "info",
+				Usage:  "device info",
+				Action: doEpsolarInfo,
+			},
+			{
+				Name:   "profile",
+				Usage:  "capability profile",
+				Action: doEpsolarProfile,
+			},
 			{
 				Name:   "rated-data",
 				Usage:  "rated-data",
@@ -120,6 +323,110 @@ var (
 					},
 				},
 			},
+			{
+				Name:  "charging",
+				Usage: "charging",
+				Commands: []*cli.Command{
+					{
+						Name:   "enable",
+						Usage:  "enable charging",
+						Action: doEpsolarChargingEnable,
+					},
+					{
+						Name:   "disable",
+						Usage:  "disable charging",
+						Action: doEpsolarChargingDisable,
+					},
+				},
+			},
+			{
+				Name:  "load",
+				Usage: "load",
+				Commands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "status",
+						Action: doEpsolarLoadStatus,
+					},
+					{
+						Name:   "on",
+						Usage:  "turn load on (manual mode)",
+						Action: doEpsolarLoadOn,
+					},
+					{
+						Name:   "off",
+						Usage:  "turn load off (manual mode)",
+						Action: doEpsolarLoadOff,
+					},
+					{
+						Name:  "test",
+						Usage: "enter load test mode and force load on",
+						Flags: []cli.Flag{
+							loadTestDisableFlag,
+						},
+						Action: doEpsolarLoadTest,
+					},
+				},
+			},
+			{
+				Name:  "load-control",
+				Usage: "load controlling mode and timers",
+				Commands: []*cli.Command{
+					{
+						Name:   "get",
+						Usage:  "get",
+						Action: doEpsolarLoadControlGet,
+					},
+					{
+						Name:  "set",
+						Usage: "set",
+						Flags: []cli.Flag{
+							loadControllingModeFlag,
+							workingTimeLength1Flag,
+							workingTimeLength2Flag,
+							turnOnTiming1Flag,
+							turnOffTiming1Flag,
+							turnOnTiming2Flag,
+							turnOffTiming2Flag,
+							lengthOfNightFlag,
+						},
+						Action: doEpsolarLoadControlSet,
+					},
+				},
+			},
+			{
+				Name:  "restore-defaults",
+				Usage: "restore system defaults",
+				Flags: []cli.Flag{
+					yesFlag,
+					backupFileFlag,
+				},
+				Action: doEpsolarRestoreDefaults,
+			},
+			{
+				Name:  "clear-statistics",
+				Usage: "clear generated energy statistics",
+				Flags: []cli.Flag{
+					yesFlag,
+					backupFileFlag,
+				},
+				Action: doEpsolarClearStatistics,
+			},
+			{
+				Name:  "simulate",
+				Usage: "serve a simulated controller over Modbus TCP",
+				Flags: []cli.Flag{
+					listenFlag,
+					simulationBatteryRatedVoltageFlag,
+					simulationBatteryCapacityFlag,
+					simulationInitialSOCFlag,
+					simulationPVPeakPowerFlag,
+					simulationLoadPowerFlag,
+					simulationDayLengthFlag,
+					simulationIntervalFlag,
+				},
+				Action: doEpsolarSimulate,
+			},
 			{
 				Name:   "prometheus",
 				Usage:  "prometheus",
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/urfave/cli/v3"
+)
+
+func doEpsolarRestoreDefaults(ctx context.Context, cmd *cli.Command) error {
+	dev, err := prepareMaintenance(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.RestoreDefaultsContext(ctx)
+}
+
+func doEpsolarClearStatistics(ctx context.Context, cmd *cli.Command) error {
+	dev, err := prepareMaintenance(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return dev.ClearStatisticsContext(ctx)
+}
+
+func prepareMaintenance(ctx context.Context, cmd *cli.Command) (*epsolar.Dev, error) {
+	if !cmd.Bool(yesFlag.Name) {
+		return nil, fmt.Errorf("refusing to %s without --%s", cmd.Name, yesFlag.Name)
+	}
+
+	backupFile := cmd.String(backupFileFlag.Name)
+	if backupFile == "" {
+		backupFile = fmt.Sprintf("epsolar-parameters-%s.json", time.Now().Format("20060102-150405"))
+	}
+
+	dev, err := newDev(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := dev.ReadParametersContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.MarshalIndent(parameters, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(backupFile, jsonBytes, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "parameters backed up to %s\n", backupFile)
+
+	return dev, nil
+}
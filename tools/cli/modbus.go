@@ -1,9 +1,9 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/simonvetter/modbus"
 	"github.com/urfave/cli/v3"
@@ -22,12 +22,37 @@ func parseParity(s string) (uint, error) {
 	return 0, fmt.Errorf("invalid parity: %s", s)
 }
 
-func newModbusClient(cmd *cli.Command, configurer func(cfg *modbus.ClientConfiguration)) (*modbus.ModbusClient, error) {
+func validateModbusURL(s string) error {
+	scheme, _, ok := strings.Cut(s, "://")
+	if !ok {
+		return fmt.Errorf("invalid url: %s", s)
+	}
+	switch scheme {
+	case "rtu", "tcp", "tcp+tls", "udp", "rtuovertcp", "rtuoverudp":
+		return nil
+	}
+	return fmt.Errorf("unsupported url scheme: %s", scheme)
+}
+
+func newModbusClientConfiguration(cmd *cli.Command, configurer func(cfg *modbus.ClientConfiguration)) (*modbus.ClientConfiguration, error) {
+	url := cmd.String(urlFlag.Name)
 	serialPort := cmd.String(serialPortFlag.Name)
 	baudRate := cmd.Uint(baudRateFlag.Name)
 	dataBits := cmd.Uint(dataBitsFlag.Name)
 	parityString := cmd.String(parityFlag.Name)
 	stopBits := cmd.Uint(stopBitsFlag.Name)
+	timeout := cmd.Duration(timeoutFlag.Name)
+
+	tlsClientCert := cmd.String(tlsClientCertFlag.Name)
+	tlsClientKey := cmd.String(tlsClientKeyFlag.Name)
+	tlsRootCAs := cmd.String(tlsRootCAsFlag.Name)
+
+	if url == "" {
+		if serialPort == "" {
+			return nil, fmt.Errorf("either --%s or --%s must be specified", serialPortFlag.Name, urlFlag.Name)
+		}
+		url = "rtu://" + serialPort
+	}
 
 	parity, err := parseParity(parityString)
 	if err != nil {
@@ -35,21 +60,29 @@ func newModbusClient(cmd *cli.Command, configurer func(cfg *modbus.ClientConfigu
 	}
 
 	config := &modbus.ClientConfiguration{
-		URL:      "rtu://" + serialPort,
+		URL:      url,
 		Speed:    baudRate,
 		DataBits: dataBits,
 		Parity:   parity,
 		StopBits: stopBits,
-		Timeout:  1 * time.Second,
+		Timeout:  timeout,
+	}
+	if (tlsClientCert != "") || (tlsClientKey != "") {
+		cert, err := tls.LoadX509KeyPair(tlsClientCert, tlsClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.TLSClientCert = &cert
+	}
+	if tlsRootCAs != "" {
+		config.TLSRootCAs, err = modbus.LoadCertPool(tlsRootCAs)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if configurer != nil {
 		configurer(config)
 	}
 
-	client, err := modbus.NewClient(config)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+	return config, nil
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ngyewch/epever-solar"
+	"github.com/ngyewch/epever-solar/simulator"
+	"github.com/urfave/cli/v3"
+)
+
+func doEpsolarSimulate(ctx context.Context, cmd *cli.Command) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	var level epsolar.BatteryRatedVoltageLevel
+	switch cmd.Uint(simulationBatteryRatedVoltageFlag.Name) {
+	case 12:
+		level = epsolar.BatteryRatedVoltageLevel12V
+	case 24:
+		level = epsolar.BatteryRatedVoltageLevel24V
+	default:
+		return fmt.Errorf("unsupported battery rated voltage: %d", cmd.Uint(simulationBatteryRatedVoltageFlag.Name))
+	}
+
+	simulation, err := simulator.NewSimulation(simulator.SimulationConfig{
+		BatteryRatedVoltageLevel: level,
+		BatteryCapacity:          cmd.Float(simulationBatteryCapacityFlag.Name),
+		InitialSOC:               cmd.Float(simulationInitialSOCFlag.Name),
+		PVPeakPower:              cmd.Float(simulationPVPeakPowerFlag.Name),
+		LoadPower:                cmd.Float(simulationLoadPowerFlag.Name),
+		DayLength:                cmd.Duration(simulationDayLengthFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	server, err := simulation.Controller().NewServer(cmd.String(listenFlag.Name))
+	if err != nil {
+		return err
+	}
+	err = server.Start()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = server.Stop()
+	}()
+
+	slog.Info("serving simulated controller",
+		slog.String("url", cmd.String(listenFlag.Name)),
+	)
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				slog.Info(simulation.String())
+			}
+		}
+	}()
+
+	err = simulation.Run(ctx, cmd.Duration(simulationIntervalFlag.Name))
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,279 @@
+package epsolar
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simonvetter/modbus"
+)
+
+type RegisterType uint8
+
+const (
+	HoldingRegister RegisterType = iota
+	InputRegister
+)
+
+// Transport performs Modbus transactions on behalf of Dev.
+//
+// Implementations should report Modbus exceptions using the error values defined by
+// github.com/simonvetter/modbus (e.g. modbus.ErrIllegalDataAddress), as Dev relies on
+// errors.Is to detect unsupported registers.
+type Transport interface {
+	SetUnitId(id uint8) error
+	ReadRegisters(addr uint16, quantity uint16, regType RegisterType) ([]uint16, error)
+	ReadCoils(addr uint16, quantity uint16) ([]bool, error)
+	ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error)
+	WriteRegister(addr uint16, value uint16) error
+	WriteRegisters(addr uint16, values []uint16) error
+	WriteCoil(addr uint16, value bool) error
+}
+
+// DeviceIdentifier is implemented by Transports that support Modbus Read Device Identification (function
+// 0x2B/0x0E). ReadDeviceIdentification returns the basic and regular objects reported by the device.
+type DeviceIdentifier interface {
+	ReadDeviceIdentification() (map[DeviceIdentificationObject]string, error)
+}
+
+// ModbusClientTransport adapts a *modbus.ModbusClient to Transport.
+//
+// It also implements DeviceIdentifier if created with NewModbusClientTransportWithConfiguration. As
+// github.com/simonvetter/modbus does not support the function, the client is closed for the duration of the
+// request, which is made over a separate link opened from the client configuration, and then reopened. The
+// request is made at most once per unit ID; later calls return the first result.
+type ModbusClientTransport struct {
+	mc             *modbus.ModbusClient
+	conf           *modbus.ClientConfiguration
+	unitId         uint8
+	identification map[uint8]deviceIdentificationResult
+}
+
+type deviceIdentificationResult struct {
+	objects map[DeviceIdentificationObject]string
+	err     error
+}
+
+func NewModbusClientTransport(mc *modbus.ModbusClient) *ModbusClientTransport {
+	return &ModbusClientTransport{
+		mc:     mc,
+		unitId: 1,
+	}
+}
+
+// NewModbusClientTransportWithConfiguration returns a ModbusClientTransport that supports device
+// identification. conf must be the configuration mc was created with, and mc must be open.
+//
+// Reading the device identification closes mc and reopens it, so mc must not be used by others meanwhile. If
+// mc cannot be reopened, the error from Open is returned and mc is left closed.
+func NewModbusClientTransportWithConfiguration(mc *modbus.ModbusClient, conf *modbus.ClientConfiguration) *ModbusClientTransport {
+	t := NewModbusClientTransport(mc)
+	t.conf = conf
+	return t
+}
+
+// SetUnitId also resets the client's encoding, as Transport returns raw register words and the
+// *modbus.ModbusClient may be shared with code that changes it.
+func (t *ModbusClientTransport) SetUnitId(id uint8) error {
+	err := t.mc.SetUnitId(id)
+	if err != nil {
+		return err
+	}
+	t.unitId = id
+	err = t.mc.SetEncoding(modbus.BIG_ENDIAN, modbus.LOW_WORD_FIRST)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ModbusClientTransport) ReadRegisters(addr uint16, quantity uint16, regType RegisterType) ([]uint16, error) {
+	switch regType {
+	case HoldingRegister:
+		return t.mc.ReadRegisters(addr, quantity, modbus.HOLDING_REGISTER)
+	case InputRegister:
+		return t.mc.ReadRegisters(addr, quantity, modbus.INPUT_REGISTER)
+	default:
+		return nil, fmt.Errorf("unsupported register type: %d", regType)
+	}
+}
+
+func (t *ModbusClientTransport) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	return t.mc.ReadCoils(addr, quantity)
+}
+
+func (t *ModbusClientTransport) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	return t.mc.ReadDiscreteInputs(addr, quantity)
+}
+
+func (t *ModbusClientTransport) WriteRegister(addr uint16, value uint16) error {
+	return t.mc.WriteRegister(addr, value)
+}
+
+func (t *ModbusClientTransport) WriteRegisters(addr uint16, values []uint16) error {
+	return t.mc.WriteRegisters(addr, values)
+}
+
+func (t *ModbusClientTransport) WriteCoil(addr uint16, value bool) error {
+	return t.mc.WriteCoil(addr, value)
+}
+
+func (t *ModbusClientTransport) ReadDeviceIdentification() (map[DeviceIdentificationObject]string, error) {
+	if t.conf == nil {
+		return nil, modbus.ErrIllegalFunction
+	}
+	if result, ok := t.identification[t.unitId]; ok {
+		return result.objects, result.err
+	}
+
+	objects, err := t.readDeviceIdentification()
+	if t.identification == nil {
+		t.identification = make(map[uint8]deviceIdentificationResult)
+	}
+	t.identification[t.unitId] = deviceIdentificationResult{
+		objects: objects,
+		err:     err,
+	}
+	return objects, err
+}
+
+func (t *ModbusClientTransport) readDeviceIdentification() (map[DeviceIdentificationObject]string, error) {
+	err := t.mc.Close()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := func() (map[DeviceIdentificationObject]string, error) {
+		link, err := openIdentificationLink(t.conf)
+		if err != nil {
+			return nil, err
+		}
+		defer func(link *identificationLink) {
+			_ = link.Close()
+		}(link)
+
+		return readDeviceIdentification(link, t.unitId)
+	}()
+	openErr := t.mc.Open()
+	if openErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("reopening Modbus client after device identification failed (%v): %w", err, openErr)
+		}
+		return nil, fmt.Errorf("reopening Modbus client after device identification: %w", openErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// ---
+
+// The mc* methods perform a single Modbus transaction, provided ctx is not done, honouring the inter-frame
+// delay and retrying transient failures according to the retry policy. Addresses outside the profile fail with
+// modbus.ErrIllegalDataAddress without a transaction.
+
+func (dev *Dev) mcReadRegister(ctx context.Context, addr uint16, regType RegisterType) (uint16, error) {
+	v, err := dev.mcReadRegisters(ctx, addr, 1, regType)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
+
+func (dev *Dev) mcReadRegisters(ctx context.Context, addr uint16, quantity uint16, regType RegisterType) ([]uint16, error) {
+	if !dev.profile.supportsRange(registerSpace(regType), addr, quantity) {
+		return nil, modbus.ErrIllegalDataAddress
+	}
+	return dev.mcReadRegisterRange(ctx, addr, quantity, regType)
+}
+
+// mcReadRegisterRange is mcReadRegisters without the profile check, for blocks that may span unsupported
+// registers.
+func (dev *Dev) mcReadRegisterRange(ctx context.Context, addr uint16, quantity uint16, regType RegisterType) ([]uint16, error) {
+	var v []uint16
+	err := dev.transact(ctx, func() error {
+		var err error
+		v, err = dev.mc.ReadRegisters(addr, quantity, regType)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (dev *Dev) mcReadCoil(ctx context.Context, addr uint16) (bool, error) {
+	if !dev.profile.supportsAddress(coilSpace, addr) {
+		return false, modbus.ErrIllegalDataAddress
+	}
+	var v []bool
+	err := dev.transact(ctx, func() error {
+		var err error
+		v, err = dev.mc.ReadCoils(addr, 1)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return v[0], nil
+}
+
+func (dev *Dev) mcReadDiscreteInput(ctx context.Context, addr uint16) (bool, error) {
+	if !dev.profile.supportsAddress(discreteInputSpace, addr) {
+		return false, modbus.ErrIllegalDataAddress
+	}
+	var v []bool
+	err := dev.transact(ctx, func() error {
+		var err error
+		v, err = dev.mc.ReadDiscreteInputs(addr, 1)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return v[0], nil
+}
+
+func (dev *Dev) mcWriteRegister(ctx context.Context, addr uint16, value uint16) error {
+	if !dev.profile.supportsAddress(holdingRegisterSpace, addr) {
+		return modbus.ErrIllegalDataAddress
+	}
+	return dev.transact(ctx, func() error {
+		return dev.mc.WriteRegister(addr, value)
+	})
+}
+
+func (dev *Dev) mcWriteRegisters(ctx context.Context, addr uint16, values []uint16) error {
+	if !dev.profile.supportsRange(holdingRegisterSpace, addr, uint16(len(values))) {
+		return modbus.ErrIllegalDataAddress
+	}
+	return dev.transact(ctx, func() error {
+		return dev.mc.WriteRegisters(addr, values)
+	})
+}
+
+func (dev *Dev) mcWriteCoil(ctx context.Context, addr uint16, value bool) error {
+	if !dev.profile.supportsAddress(coilSpace, addr) {
+		return modbus.ErrIllegalDataAddress
+	}
+	return dev.transact(ctx, func() error {
+		return dev.mc.WriteCoil(addr, value)
+	})
+}
+
+func (dev *Dev) mcReadDeviceIdentification(ctx context.Context) (map[DeviceIdentificationObject]string, error) {
+	identifier, ok := dev.mc.(DeviceIdentifier)
+	if !ok {
+		return nil, modbus.ErrIllegalFunction
+	}
+	// Controllers that do not implement the function often do not answer at all, so timeouts are not retried.
+	var v map[DeviceIdentificationObject]string
+	err := dev.transactWithRetryPolicy(ctx, RetryPolicy{}, func() error {
+		var err error
+		v, err = identifier.ReadDeviceIdentification()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
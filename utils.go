@@ -7,3 +7,10 @@ func checkBit(v uint16, bitNo int) bool {
 func getBits(v uint16, bitNo int, mask uint16) uint16 {
 	return (v >> bitNo) & mask
 }
+
+func setBit(v uint16, bitNo int, set bool) uint16 {
+	if set {
+		return v | (1 << bitNo)
+	}
+	return v &^ (1 << bitNo)
+}